@@ -0,0 +1,194 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+)
+
+// DecodeOptions allows to configure how NDEF data is parsed.
+//
+// The zero value parses data strictly, as Message.Unmarshal does.
+type DecodeOptions struct {
+	// Lenient downgrades some violations of the specification, which
+	// are common in tags in the field, to warnings. A best-effort
+	// Message is returned along with them:
+	//
+	//   - A missing MB or ME flag, or MB/ME flags set in middle
+	//     records, are fixed. A record with the MB flag set after the
+	//     first one is assumed to start a new message.
+	//   - Data which cannot be parsed after the first record is
+	//     considered trailing garbage and ignored.
+	//   - Chunks with the SR flag set but a 4-byte PAYLOAD_LENGTH are
+	//     recovered. This needs the rest of the input at hand, so it
+	//     is not done by the Decoder.
+	//   - Type names and IDs with non-ASCII characters are accepted.
+	Lenient bool
+
+	// Registry provides the RecordPayload types returned by
+	// Record.Payload(), including those of records nested in other
+	// payloads, like Smart Posters. Local types in nested records are
+	// resolved in the context of the record holding them (see
+	// Registry.RegisterLocal). DefaultRegistry is used when unset.
+	Registry *Registry
+
+	// Limits bound the resources used when decoding, including the
+	// decoding of nested messages. DefaultLimits are used when unset.
+	// Exceeding them is an error even in lenient mode.
+	Limits *Limits
+
+	// ZeroCopy makes the payloads of the decoded records alias the
+	// input instead of copying it, which saves allocations. The input
+	// must not be modified while the Message is in use, and the payload
+	// bytes obtained from the records, i.e. generic.Payload.Payload,
+	// must not be modified either, as they share memory with the input.
+	// Payloads of chunked records are always copied when put together.
+	//
+	// The Decoder does not copy the data it reads in any case.
+	ZeroCopy bool
+
+	// nesting level of the message being decoded
+	depth int
+	// Well-Known type of the record holding the message being decoded,
+	// which gives meaning to local types.
+	parent string
+}
+
+func (o DecodeOptions) limits() Limits {
+	if o.Limits == nil {
+		return DefaultLimits
+	}
+	return *o.Limits
+}
+
+func (o DecodeOptions) registry() *Registry {
+	if o.Registry == nil {
+		return DefaultRegistry
+	}
+	return o.Registry
+}
+
+// WithParent returns the options to decode a Message nested in the
+// payload of a record with the given TNF and type, one level deeper.
+// Local types in the nested Message are resolved in the context of the
+// parent when it is an NFC Forum Well-Known type (see
+// Registry.RegisterLocal). See OptionsDecoder.
+func (o DecodeOptions) WithParent(tnf TNF, typ string) DecodeOptions {
+	o.depth++
+	o.parent = ""
+	if tnf == NFCForumWellKnownType {
+		o.parent = typ
+	}
+	return o
+}
+
+// Unmarshal parses a byte slice into a Message, as Message.Unmarshal,
+// but following the options. Options obtained with WithParent fail when
+// the message is nested deeper than Limits.MaxDepth.
+//
+// Returns the number of bytes processed, the list of problems which were
+// ignored in lenient mode and any error which prevented parsing the
+// Message.
+func (o DecodeOptions) Unmarshal(buf []byte, m *Message) (n int, warnings []*ParseError, err error) {
+	if err := o.limits().checkDepth(o.depth); err != nil {
+		return 0, nil, err
+	}
+	st := newDecodeState(o)
+	src := &sliceChunkSource{buf: buf}
+	err = m.readRecords(src, st)
+	return src.off, st.warnings, err
+}
+
+// UnmarshalRecord parses a byte slice into a Record, as Record.Unmarshal,
+// but following the options. The payload of the record counts towards
+// both MaxPayloadLength and MaxTotalPayloadLength.
+//
+// Returns the number of bytes processed, the list of problems which were
+// ignored in lenient mode and any error which prevented parsing the
+// Record.
+func (o DecodeOptions) UnmarshalRecord(buf []byte, r *Record) (n int, warnings []*ParseError, err error) {
+	st := newDecodeState(o)
+	src := &sliceChunkSource{buf: buf}
+	err = r.readChunks(src, st)
+	return src.off, st.warnings, err
+}
+
+// decodeState carries the options and keeps track of the position
+// while parsing.
+type decodeState struct {
+	opts     DecodeOptions
+	warnings []*ParseError
+
+	record int   // index of the record being parsed
+	chunk  int   // index of the chunk being parsed
+	offset int64 // offset of the chunk being parsed
+
+	ownInput bool // the input is not shared with the caller
+
+	recordPayload uint64 // payload length of the record being parsed
+	totalPayload  uint64 // payload length of the message being parsed
+}
+
+func newDecodeState(opts DecodeOptions) *decodeState {
+	return &decodeState{
+		opts:   opts,
+		record: -1,
+		chunk:  -1,
+	}
+}
+
+// checkPayload returns an error if the payload of the given chunk does
+// not fit in the limits.
+func (st *decodeState) checkPayload(chunk *recordChunk, offset int64) error {
+	err := st.opts.limits().checkPayload(st.recordPayload,
+		st.totalPayload, chunk.PayloadLength)
+	return chunk.locate(err, offset)
+}
+
+// addPayload accounts for the payload of the given chunk.
+func (st *decodeState) addPayload(chunk *recordChunk) error {
+	if err := st.checkPayload(chunk, chunk.offset); err != nil {
+		return err
+	}
+	st.recordPayload += chunk.PayloadLength
+	st.totalPayload += chunk.PayloadLength
+	return nil
+}
+
+// aliasInput returns true when parsed chunks can keep references to
+// the input.
+func (st *decodeState) aliasInput() bool {
+	return st != nil && (st.opts.ZeroCopy || st.ownInput)
+}
+
+func (st *decodeState) lenient() bool {
+	return st != nil && st.opts.Lenient
+}
+
+// warn records err as a warning when decoding leniently, in which case
+// it returns true. A nil decodeState is strict.
+func (st *decodeState) warn(err error) bool {
+	var perr *ParseError
+	if !st.lenient() || !errors.As(err, &perr) ||
+		errors.Is(err, ErrLimitExceeded) {
+		return false
+	}
+	setIndex(perr, st.record, st.chunk)
+	st.warnings = append(st.warnings, perr)
+	return true
+}
@@ -0,0 +1,204 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/media"
+)
+
+func checkWarnings(t *testing.T, warnings []*ParseError, expected ...error) {
+	t.Helper()
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected %d warnings but got %d: %v", len(expected),
+			len(warnings), warnings)
+	}
+	for i, w := range warnings {
+		if !errors.Is(w, expected[i]) {
+			t.Errorf("expected %s but got %s", expected[i], w)
+		}
+		t.Log(w)
+	}
+}
+
+func TestLenientTrailingGarbage(t *testing.T) {
+	mBytes, _ := NewURIMessage("http://s.com").Marshal()
+	mBytes[0] &^= 0x40                              // no ME
+	mBytes = append(mBytes, 0xfe, 0x00, 0x00, 0x00) // TLV terminator and padding
+
+	m := &Message{}
+	if _, err := m.Unmarshal(mBytes); err == nil {
+		t.Fatal("strict unmarshaling should fail")
+	}
+
+	n, warnings, err := DecodeOptions{Lenient: true}.Unmarshal(mBytes, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkWarnings(t, warnings, ErrSingleChunked, ErrNoMessageEnd)
+	if warnings[0].Record != 1 || warnings[0].Offset != 10 {
+		t.Error("unexpected location:", warnings[0])
+	}
+	if n != len(mBytes) {
+		t.Error("unexpected number of bytes processed:", n)
+	}
+	if len(m.Records) != 1 || m.String() != "urn:nfc:wkt:U:http://s.com" {
+		t.Error("unexpected message:", m)
+	}
+	if _, err := m.Marshal(); err != nil {
+		t.Error("the message should have been fixed:", err)
+	}
+
+	// Garbage in the first record cannot be recovered
+	_, _, err = DecodeOptions{Lenient: true}.Unmarshal(mBytes[10:], m)
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestLenientMissingME(t *testing.T) {
+	first, _ := NewTextMessage("first", "en").Marshal()
+	second, _ := NewTextMessage("second", "en").Marshal()
+	first[0] &^= 0x40 // no ME
+	stream := append(append([]byte{}, first...), second...)
+
+	opts := DecodeOptions{Lenient: true}
+	m := &Message{}
+	n, warnings, err := opts.Unmarshal(stream, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkWarnings(t, warnings, ErrNoMessageEnd)
+	if n != len(first) || m.String() != "urn:nfc:wkt:T:first" {
+		t.Error("the first message should end before the second")
+	}
+
+	dec := NewDecoder(bytes.NewReader(stream))
+	dec.Options = opts
+	for _, expected := range []string{"first", "second"} {
+		if err := dec.Decode(m); err != nil {
+			t.Fatal(err)
+		}
+		if m.String() != "urn:nfc:wkt:T:"+expected {
+			t.Error("unexpected message:", m)
+		}
+	}
+	checkWarnings(t, dec.Warnings())
+}
+
+func TestLenientShortRecord(t *testing.T) {
+	mBytes := []byte{
+		0xd1,                   // MB, ME, SR, TNF=1
+		0x01,                   // Type length
+		0x00, 0x00, 0x00, 0x06, // Payload length (4 bytes)
+		'U',
+		0x03, 's', '.', 'c', 'o', 'm',
+	}
+	m := &Message{}
+	if _, err := m.Unmarshal(mBytes); err == nil {
+		t.Fatal("strict unmarshaling should fail")
+	}
+	_, warnings, err := DecodeOptions{Lenient: true}.Unmarshal(mBytes, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkWarnings(t, warnings, ErrBadShortRecord)
+	if m.String() != "urn:nfc:wkt:U:http://s.com" {
+		t.Error("unexpected message:", m)
+	}
+}
+
+func TestLenientNonASCII(t *testing.T) {
+	r := NewRecord(NFCForumExternalType, "tést", "ïd", nil)
+	r.chunks[0].SR = true
+	mBytes := r.chunks[0].appendHeader(nil)
+
+	m := &Message{}
+	if _, err := m.Unmarshal(mBytes); !errors.Is(err, ErrNonASCIIType) {
+		t.Fatal("strict unmarshaling should fail:", err)
+	}
+	_, warnings, err := DecodeOptions{Lenient: true}.Unmarshal(mBytes, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkWarnings(t, warnings, ErrNonASCIIType, ErrNonASCIIID)
+	if m.Records[0].Type() != "tést" || m.Records[0].ID() != "ïd" {
+		t.Error("unexpected record")
+	}
+
+	r = &Record{}
+	if _, err := r.Unmarshal(mBytes); !errors.Is(err, ErrNonASCIIType) {
+		t.Fatal("strict unmarshaling should fail:", err)
+	}
+	_, warnings, err = DecodeOptions{Lenient: true}.UnmarshalRecord(mBytes, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkWarnings(t, warnings, ErrNonASCIIType, ErrNonASCIIID)
+	if r.Type() != "tést" || r.ID() != "ïd" {
+		t.Error("unexpected record")
+	}
+}
+
+func TestZeroCopy(t *testing.T) {
+	mBytes, err := NewMediaMessage("a/b", []byte{1, 2, 3}).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	payloadOf := func(opts DecodeOptions) []byte {
+		m := &Message{}
+		if _, _, err := opts.Unmarshal(mBytes, m); err != nil {
+			t.Fatal(err)
+		}
+		pl, err := m.Records[0].Payload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pl.(*media.Payload).Payload
+	}
+
+	copied := payloadOf(DecodeOptions{})
+	aliased := payloadOf(DecodeOptions{ZeroCopy: true})
+	mBytes[len(mBytes)-1] = 4
+	if copied[2] != 3 {
+		t.Error("payload should not alias the input")
+	}
+	if aliased[2] != 4 {
+		t.Error("payload should alias the input")
+	}
+
+	// appending to the payload does not overwrite the input.
+	mBytes = append(mBytes, 0xff)
+	aliased = payloadOf(DecodeOptions{ZeroCopy: true})
+	_ = append(aliased, 0)
+	if mBytes[len(mBytes)-1] != 0xff {
+		t.Error("appending to the payload should not modify the input")
+	}
+
+	r := &Record{}
+	if _, _, err := (DecodeOptions{ZeroCopy: true}).UnmarshalRecord(mBytes, r); err != nil {
+		t.Fatal(err)
+	}
+	mBytes[len(mBytes)-2] = 5
+	if r.payloadBytes()[2] != 5 {
+		t.Error("record payload should alias the input")
+	}
+}
@@ -0,0 +1,158 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Decoder reads and decodes NDEF Messages from an input stream.
+//
+// Record chunks are read incrementally, so the input does not need to
+// be fully available in memory. The same checks performed by
+// Message.Unmarshal are applied to every decoded Message.
+type Decoder struct {
+	r   *bufio.Reader
+	off int64 // bytes read
+
+	// Options allows to configure decoding. See DecodeOptions for
+	// the limitations of lenient decoding with a Decoder.
+	Options DecodeOptions
+
+	warnings []*ParseError
+
+	// chunks which were read but belong to the next Message
+	pending         []*recordChunk
+	pendingWarnings []*ParseError
+}
+
+// NewDecoder returns a new Decoder that reads from r. The Decoder
+// introduces its own buffering and may read data from r beyond the
+// Messages requested.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{
+		r: bufio.NewReader(r),
+	}
+}
+
+// Decode reads the next NDEF Message from the input and stores it in m.
+// Messages are expected to follow each other back-to-back in the input.
+//
+// Returns io.EOF when there are no more messages to read. Errors in the
+// data are returned as a *ParseError, with offsets counting from the
+// beginning of the input. If the input ends in the middle of a record
+// chunk, the error wraps ErrTruncated.
+func (d *Decoder) Decode(m *Message) error {
+	d.warnings = nil
+	more, err := d.more()
+	if err != nil {
+		return err
+	}
+	if !more {
+		return io.EOF
+	}
+
+	st := newDecodeState(d.Options)
+	st.ownInput = true
+	for _, w := range d.pendingWarnings {
+		w.Record = 0
+		st.warnings = append(st.warnings, w)
+	}
+	d.pendingWarnings = nil
+	err = m.readRecords(d, st)
+	d.warnings = st.warnings
+	return err
+}
+
+// Warnings returns the problems which were ignored when decoding the
+// last Message in lenient mode.
+func (d *Decoder) Warnings() []*ParseError {
+	return d.warnings
+}
+
+func (d *Decoder) more() (bool, error) {
+	if len(d.pending) > 0 {
+		return true, nil
+	}
+	_, err := d.r.Peek(1)
+	if err == io.EOF {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// unread keeps the chunks and warnings for the next Message.
+func (d *Decoder) unread(chunks []*recordChunk, warnings []*ParseError) {
+	d.pending = append(chunks, d.pending...)
+	d.pendingWarnings = append(d.pendingWarnings, warnings...)
+}
+
+// next reads a chunk header field by field, which tells us how long the
+// rest of the chunk is, and then parses the whole chunk.
+func (d *Decoder) next(st *decodeState) (*recordChunk, error) {
+	if len(d.pending) > 0 {
+		chunk := d.pending[0]
+		d.pending = d.pending[1:]
+		return chunk, nil
+	}
+
+	var buf bytes.Buffer
+	firstByte, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte(firstByte)
+	headerLen := int64(chunkHeaderLen(firstByte))
+	if _, err := io.CopyN(&buf, d.r, headerLen-1); err != nil {
+		return nil, d.fail(buf.Bytes(), err, st)
+	}
+
+	chunk := &recordChunk{}
+	chunk.unmarshalFlags(firstByte)
+	chunk.unmarshalHeader(buf.Bytes())
+	if err := st.checkPayload(chunk, d.off); err != nil {
+		return nil, err
+	}
+
+	// Let the buffer grow as data arrives instead of trusting the
+	// declared lengths for allocating it.
+	if _, err := io.CopyN(&buf, d.r, int64(chunk.bodyLen())); err != nil {
+		return nil, d.fail(buf.Bytes(), err, st)
+	}
+
+	st.offset = d.off
+	n, err := chunk.unmarshal(buf.Bytes(), st)
+	chunk.offset = d.off
+	d.off += int64(n)
+	return chunk, err
+}
+
+// fail returns the error for a chunk which could not be read completely.
+// When the input has ended, this is the error that Unmarshal returns for
+// the partial chunk.
+func (d *Decoder) fail(partial []byte, err error, st *decodeState) error {
+	if err != io.EOF {
+		return err
+	}
+	st.offset = d.off
+	_, err = new(recordChunk).unmarshal(partial, st)
+	d.off += int64(len(partial))
+	return err
+}
@@ -0,0 +1,172 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func ExampleDecoder() {
+	var stream bytes.Buffer
+	for _, m := range []*Message{
+		NewTextMessage("first", "en"),
+		NewURIMessage("https://github.com/hsanjuan/go-ndef"),
+	} {
+		mBytes, _ := m.Marshal()
+		stream.Write(mBytes)
+	}
+
+	dec := NewDecoder(&stream)
+	for {
+		var m Message
+		err := dec.Decode(&m)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(&m)
+	}
+	// Output:
+	// urn:nfc:wkt:T:first
+	// urn:nfc:wkt:U:https://github.com/hsanjuan/go-ndef
+}
+
+func TestDecoderMatchesUnmarshal(t *testing.T) {
+	chunked := &Record{chunks: []*recordChunk{
+		newChunk(NFCForumWellKnownType, "T", "", []byte("\x02en")),
+		newChunk(Unchanged, "", "", []byte("chunked")),
+	}}
+	chunked.chunks[0].CF = true
+	chunked.chunks[0].ME = false
+	chunked.chunks[1].MB = false
+
+	msgs := []*Message{
+		NewTextMessage("abc", "en"),
+		NewMediaMessage("text/plain", bytes.Repeat([]byte("a"), 300)),
+		NewMessageFromRecords(
+			NewRecord(NFCForumExternalType, "test", "#ab", nil),
+			NewURIRecord("http://a.b"),
+		),
+		{Records: []*Record{chunked}},
+	}
+
+	var stream bytes.Buffer
+	for _, m := range msgs {
+		mBytes, err := m.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream.Write(mBytes)
+	}
+	all := stream.Bytes()
+
+	// One byte at a time to exercise short reads.
+	dec := NewDecoder(iotest.OneByteReader(bytes.NewReader(all)))
+	off := 0
+	for i := range msgs {
+		var expected Message
+		n, err := expected.Unmarshal(all[off:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		off += n
+
+		var m Message
+		if err := dec.Decode(&m); err != nil {
+			t.Fatalf("message %d: %s", i, err)
+		}
+		expBytes, _ := expected.Marshal()
+		mBytes, err := m.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(mBytes, expBytes) {
+			t.Errorf("message %d: decoded message does not match", i)
+		}
+		if m.String() != expected.String() {
+			t.Errorf("message %d: unexpected contents: %s", i, &m)
+		}
+	}
+
+	var m Message
+	if err := dec.Decode(&m); err != io.EOF {
+		t.Error("expected io.EOF after the last message:", err)
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	mBytes, err := NewTextMessage("abc", "en").Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m Message
+	dec := NewDecoder(bytes.NewReader(mBytes[:len(mBytes)-2]))
+	err = dec.Decode(&m)
+	if !errors.Is(err, ErrTruncated) {
+		t.Error("expected ErrTruncated:", err)
+	}
+	_, expected := new(Message).Unmarshal(mBytes[:len(mBytes)-2])
+	if err.Error() != expected.Error() {
+		t.Errorf("expected %s, got %s", expected, err)
+	}
+
+	// Same checks as in Message.Unmarshal
+	noME := append([]byte{}, mBytes...)
+	noME[0] &^= 0x40
+	_, expected = new(Message).Unmarshal(noME)
+	dec = NewDecoder(bytes.NewReader(noME))
+	err = dec.Decode(&m)
+	if err == nil || expected == nil || err.Error() != expected.Error() {
+		t.Errorf("expected %s, got %s", expected, err)
+	}
+
+	// Offsets count from the start of the stream
+	stream := append(append([]byte{}, mBytes...), mBytes[:len(mBytes)-2]...)
+	dec = NewDecoder(bytes.NewReader(stream))
+	if err := dec.Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	var perr *ParseError
+	err = dec.Decode(&m)
+	if !errors.As(err, &perr) || perr.Offset != int64(len(mBytes)+4) {
+		t.Error("unexpected error offset:", err)
+	}
+
+	dec = NewDecoder(errReader{io.ErrClosedPipe})
+	if err := dec.Decode(&m); err != io.ErrClosedPipe {
+		t.Error("expected reader error:", err)
+	}
+}
+
+// errReader is an io.Reader which always fails with err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
@@ -0,0 +1,190 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hsanjuan/go-ndef/types/absoluteuri"
+	"github.com/hsanjuan/go-ndef/types/ext"
+	"github.com/hsanjuan/go-ndef/types/media"
+)
+
+// Names of the fields compared by Diff, besides FieldType, FieldID and
+// FieldPayload.
+const (
+	FieldTNF     = "TNF"
+	FieldRecords = "RECORDS" // number of records in a message
+)
+
+// A Difference is a field which differs between two Messages.
+type Difference struct {
+	// Path holds the index of the record in the message, preceded by
+	// the indexes of the Smart Poster records containing it, if any.
+	// It is the index of the message for differences in FieldRecords.
+	Path []int
+	// Field is one of FieldTNF, FieldType, FieldID, FieldPayload or
+	// FieldRecords.
+	Field string
+	// A and B are the values of the field in each message: a TNF for
+	// FieldTNF, a string for FieldType and FieldID, a []byte for
+	// FieldPayload and an int for FieldRecords.
+	A, B interface{}
+}
+
+// String returns a readable description of the Difference.
+func (d Difference) String() string {
+	path := make([]string, len(d.Path))
+	for i, p := range d.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	where := "message"
+	if d.Field != FieldRecords {
+		where = "record"
+	}
+	if len(path) > 0 {
+		where += " " + strings.Join(path, ".")
+	}
+	return fmt.Sprintf("%s: %s: %#v != %#v", where, d.Field, d.A, d.B)
+}
+
+// Equal returns true when both Messages have the same records. See
+// Record.Equal.
+func (m *Message) Equal(other *Message) bool {
+	return len(Diff(m, other)) == 0
+}
+
+// Equal returns true when both Records have the same TNF, type, ID and
+// payload. How the records are chunked and the values of their MB, ME,
+// SR and IL flags are ignored, as they only affect their encoding.
+// Types are compared case-insensitively for Media and NFC Forum
+// External types. The payloads of Smart Posters are compared as
+// messages.
+func (r *Record) Equal(other *Record) bool {
+	return len(diffRecords(nil, r, other)) == 0
+}
+
+// Diff returns the differences between two Messages, in record order.
+// Records are compared as in Record.Equal, and the differences between
+// Smart Poster records are those of the messages in their payloads.
+func Diff(a, b *Message) []Difference {
+	return diffMessages(nil, a, b)
+}
+
+func diffMessages(path []int, a, b *Message) []Difference {
+	var aRecords, bRecords []*Record
+	if a != nil {
+		aRecords = a.Records
+	}
+	if b != nil {
+		bRecords = b.Records
+	}
+
+	var diffs []Difference
+	for i := 0; i < len(aRecords) && i < len(bRecords); i++ {
+		diffs = append(diffs,
+			diffRecords(appendPath(path, i), aRecords[i], bRecords[i])...)
+	}
+	if len(aRecords) != len(bRecords) {
+		diffs = append(diffs, Difference{
+			Path:  path,
+			Field: FieldRecords,
+			A:     len(aRecords),
+			B:     len(bRecords),
+		})
+	}
+	return diffs
+}
+
+func diffRecords(path []int, a, b *Record) []Difference {
+	if a == nil {
+		a = &Record{}
+	}
+	if b == nil {
+		b = &Record{}
+	}
+
+	var diffs []Difference
+	add := func(field string, va, vb interface{}) {
+		diffs = append(diffs, Difference{
+			Path:  path,
+			Field: field,
+			A:     va,
+			B:     vb,
+		})
+	}
+
+	if a.TNF() != b.TNF() {
+		add(FieldTNF, a.TNF(), b.TNF())
+	}
+	if !equalType(a.TNF(), a.Type(), b.Type()) {
+		add(FieldType, a.Type(), b.Type())
+	}
+	if a.ID() != b.ID() {
+		add(FieldID, a.ID(), b.ID())
+	}
+
+	aPayload := a.payloadBytes()
+	bPayload := b.payloadBytes()
+	if bytes.Equal(aPayload, bPayload) {
+		return diffs
+	}
+	aMsg, aOk := a.smartPosterMessage()
+	bMsg, bOk := b.smartPosterMessage()
+	if aOk && bOk {
+		return append(diffs, diffMessages(path, aMsg, bMsg)...)
+	}
+	add(FieldPayload, aPayload, bPayload)
+	return diffs
+}
+
+// smartPosterMessage returns the message in the payload of a Smart
+// Poster record, if it is one and it can be decoded.
+func (r *Record) smartPosterMessage() (*Message, bool) {
+	if r.TNF() != NFCForumWellKnownType || r.Type() != "Sp" {
+		return nil, false
+	}
+	sp := new(SmartPosterPayload)
+	opts := r.opts.WithParent(NFCForumWellKnownType, "Sp")
+	if err := sp.DecodeWithOptions(r.payloadBytes(), opts); err != nil {
+		return nil, false
+	}
+	return sp.Message, true
+}
+
+func equalType(tnf TNF, a, b string) bool {
+	switch tnf {
+	case MediaType:
+		return media.EqualType(a, b)
+	case NFCForumExternalType:
+		return ext.EqualType(a, b)
+	case AbsoluteURI:
+		return absoluteuri.EqualType(a, b)
+	default:
+		return a == b
+	}
+}
+
+// appendPath returns a new path with i appended.
+func appendPath(path []int, i int) []int {
+	p := make([]int, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, i)
+}
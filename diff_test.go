@@ -0,0 +1,114 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/media"
+)
+
+func TestEqual(t *testing.T) {
+	payload := bytes.Repeat([]byte("abc"), 100)
+	m := NewMessageFromRecords(
+		NewMediaRecord("text/plain", payload),
+		NewSmartPosterRecord(NewURIMessage("https://example.com")),
+	)
+
+	// chunk the media record and the record inside the smart poster
+	spBytes, err := MarshalOptions{MaxChunkSize: 4}.Marshal(
+		NewURIMessage("https://example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := NewRecord(NFCForumWellKnownType, "Sp", "", nil)
+	if err := sp.SetPayloadBytes(spBytes); err != nil {
+		t.Fatal(err)
+	}
+	chunked, err := NewChunkedRecord(MediaType, "Text/Plain", "", media.New("text/plain", payload), 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2 := NewMessageFromRecords(chunked, sp)
+
+	if !m.Equal(m2) {
+		t.Error("messages should be equal:", Diff(m, m2))
+	}
+	if !m.Records[0].Equal(m2.Records[0]) {
+		t.Error("records should be equal")
+	}
+	if m.Records[0].Equal(m2.Records[1]) {
+		t.Error("records should not be equal")
+	}
+	if !(*Message)(nil).Equal(&Message{}) {
+		t.Error("nil and empty messages should be equal")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	idRecord := NewTextRecord("hi", "en")
+	if err := idRecord.SetID("a"); err != nil {
+		t.Fatal(err)
+	}
+	a := NewMessageFromRecords(
+		NewTextRecord("hi", "en"),
+		NewSmartPosterRecord(NewMessageFromRecords(
+			NewURIRecord("https://a.com"),
+			NewTextRecord("title", "en"),
+		)),
+		NewURIRecord("https://a.com"),
+	)
+	b := NewMessageFromRecords(
+		idRecord,
+		NewSmartPosterRecord(NewMessageFromRecords(
+			NewURIRecord("https://b.com"),
+		)),
+	)
+
+	expected := []Difference{
+		{Path: []int{0}, Field: FieldID, A: "", B: "a"},
+		{Path: []int{1, 0}, Field: FieldPayload,
+			A: []byte("\x04a.com"), B: []byte("\x04b.com")},
+		{Path: []int{1}, Field: FieldRecords, A: 2, B: 1},
+		{Path: nil, Field: FieldRecords, A: 3, B: 2},
+	}
+	diffs := Diff(a, b)
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("unexpected differences: %v", diffs)
+	}
+
+	if s := diffs[1].String(); s != `record 1.0: PAYLOAD: []byte{0x4, 0x61, 0x2e, 0x63, 0x6f, 0x6d} != []byte{0x4, 0x62, 0x2e, 0x63, 0x6f, 0x6d}` {
+		t.Error("unexpected string:", s)
+	}
+	if s := diffs[3].String(); s != "message: RECORDS: 3 != 2" {
+		t.Error("unexpected string:", s)
+	}
+
+	tnf := Diff(NewMediaMessage("a/b", nil), NewExternalMessage("a/b", nil))
+	if len(tnf) != 1 || tnf[0].Field != FieldTNF {
+		t.Error("expected a TNF difference:", tnf)
+	}
+
+	uriType := Diff(NewAbsoluteURIMessage("HTTP://A.B/%7Ex", nil),
+		NewAbsoluteURIMessage("http://a.b/~x", nil))
+	if len(uriType) != 0 {
+		t.Error("absolute URI types should be normalized:", uriType)
+	}
+}
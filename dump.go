@@ -0,0 +1,212 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// dumpBytesPerLine is the number of bytes printed in every line of a dump.
+const dumpBytesPerLine = 8
+
+// Dump returns an annotated hex dump of NDEF data, with the offset and
+// meaning of every byte, chunk by chunk:
+//
+//	chunk 0 (record 0)
+//	  000000  d1                       FLAGS: MB=1 ME=1 CF=0 SR=1 IL=0 TNF=1 (NFC Forum Well-Known Type)
+//	  000001  01                       TYPE_LENGTH: 1
+//	  000002  04                       PAYLOAD_LENGTH: 4 (1 byte, SR)
+//	  000003  55                       TYPE: "U"
+//	  000004  04 61 2e 62              PAYLOAD: 4 bytes
+//
+// The data is parsed as one or more Messages, and the first error found
+// is marked below the field it affects. Data which cannot be dissected
+// any further, because it is truncated, is printed as such.
+func Dump(buf []byte) string {
+	return dump(buf, func(buf []byte) (int, error) {
+		return new(Message).Unmarshal(buf)
+	})
+}
+
+// Dump returns an annotated hex dump of the chunks of the Message, as
+// the package-level Dump() function does for bytes. Unlike Marshal, it
+// does not fail for invalid Messages, which are dumped as they are.
+func (m *Message) Dump() string {
+	var buf []byte
+	for _, r := range m.Records {
+		buf = r.appendRaw(buf)
+	}
+	return Dump(buf)
+}
+
+// Dump returns an annotated hex dump of the chunks of the Record, as
+// the package-level Dump() function does for bytes, although the data
+// is parsed as one or more Records rather than Messages.
+func (r *Record) Dump() string {
+	return dump(r.appendRaw(nil), func(buf []byte) (int, error) {
+		return new(Record).Unmarshal(buf)
+	})
+}
+
+// appendRaw appends the bytes of the chunks of the Record, as they are,
+// without checking them.
+func (r *Record) appendRaw(dst []byte) []byte {
+	for _, chunk := range r.chunks {
+		dst = chunk.appendHeader(dst)
+		dst = append(dst, chunk.Payload...)
+	}
+	return dst
+}
+
+// dump dissects buf, marking the first error returned by parse, which
+// is called repeatedly on the rest of the input until it is consumed.
+func dump(buf []byte, parse func(buf []byte) (int, error)) string {
+	var perr *ParseError
+	for off := 0; off < len(buf); {
+		n, err := parse(buf[off:])
+		if err != nil {
+			if !errors.As(err, &perr) {
+				perr = newParseError(err, "")
+			}
+			if perr.Offset >= 0 {
+				perr.Offset += int64(off)
+			}
+			break
+		}
+		off += n
+	}
+
+	d := &dumper{buf: buf, perr: perr}
+	d.dump()
+	return d.String()
+}
+
+type dumper struct {
+	strings.Builder
+	buf    []byte
+	perr   *ParseError
+	marked bool
+}
+
+func (d *dumper) dump() {
+	record, chunk := 0, 0
+	for off := 0; off < len(d.buf); {
+		fmt.Fprintf(d, "chunk %d (record %d)\n", chunk, record)
+		c, n := d.dumpChunk(off)
+		if c == nil { // truncated
+			break
+		}
+		off += n
+		if c.CF {
+			chunk++
+		} else {
+			record++
+			chunk = 0
+		}
+	}
+	if d.perr != nil && !d.marked {
+		fmt.Fprintf(d, "  !! %s\n", d.perr)
+	}
+}
+
+// dumpChunk dissects the chunk at the given offset. It returns the chunk
+// and its length, or nil if it is truncated.
+func (d *dumper) dumpChunk(off int) (*recordChunk, int) {
+	c := &recordChunk{}
+	buf := d.buf[off:]
+
+	c.unmarshalFlags(buf[0])
+	d.field(off, 1, fmt.Sprintf(
+		"%s: MB=%d ME=%d CF=%d SR=%d IL=%d TNF=%d (%s)",
+		FieldFlags, bit(c.MB), bit(c.ME), bit(c.CF), bit(c.SR), bit(c.IL),
+		c.TNF, c.TNF))
+
+	headerLen := chunkHeaderLen(buf[0])
+	if len(buf) < headerLen {
+		d.field(off+1, len(buf)-1, "truncated header")
+		return nil, 0
+	}
+	c.unmarshalHeader(buf[:headerLen])
+
+	d.field(off+1, 1, fmt.Sprintf("%s: %d", FieldTypeLength, c.TypeLength))
+	if c.SR {
+		d.field(off+2, 1, fmt.Sprintf("%s: %d (1 byte, SR)",
+			FieldPayloadLength, c.PayloadLength))
+	} else {
+		d.field(off+2, 4, fmt.Sprintf("%s: %d (4 bytes)",
+			FieldPayloadLength, c.PayloadLength))
+	}
+	if c.IL {
+		d.field(off+headerLen-1, 1, fmt.Sprintf("%s: %d",
+			FieldIDLength, c.IDLength))
+	}
+
+	for _, f := range []string{FieldType, FieldID, FieldPayload} {
+		start := c.fieldOffset(f)
+		l := c.fieldLen(f)
+		if l == 0 {
+			continue
+		}
+		if uint64(len(buf)-start) < l {
+			d.field(off+start, len(buf)-start, fmt.Sprintf(
+				"%s: truncated, %d of %d bytes", f, len(buf)-start, l))
+			return nil, 0
+		}
+		field := buf[start : start+int(l)]
+		switch f {
+		case FieldPayload:
+			d.field(off+start, len(field),
+				fmt.Sprintf("%s: %d bytes", f, len(field)))
+		default:
+			d.field(off+start, len(field), fmt.Sprintf("%s: %q", f, field))
+		}
+	}
+	return c, headerLen + int(c.bodyLen())
+}
+
+// field prints the n bytes of a field at the given offset, followed by
+// the error if it is located in them.
+func (d *dumper) field(off, n int, desc string) {
+	for i := 0; i < n || i == 0; i += dumpBytesPerLine {
+		end := i + dumpBytesPerLine
+		if end > n {
+			end = n
+		}
+		hex := strings.TrimSpace(fmtBytes(d.buf[off+i:off+end], dumpBytesPerLine))
+		if i > 0 {
+			desc = ""
+		}
+		line := fmt.Sprintf("  %06x  %-24s %s", off+i, hex, desc)
+		d.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+
+	if d.perr != nil && !d.marked && d.perr.Offset >= int64(off) &&
+		(d.perr.Offset < int64(off+n) || n == 0 && d.perr.Offset == int64(off)) {
+		fmt.Fprintf(d, "  !! %s\n", d.perr)
+		d.marked = true
+	}
+}
+
+func bit(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,98 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/generic"
+)
+
+func TestDump(t *testing.T) {
+	r := NewMediaRecord("a/b", []byte("0123456789"))
+	if err := r.SetID("i"); err != nil {
+		t.Fatal(err)
+	}
+	expected := `chunk 0 (record 0)
+  000000  da                       FLAGS: MB=1 ME=1 CF=0 SR=1 IL=1 TNF=2 (Media Type)
+  000001  03                       TYPE_LENGTH: 3
+  000002  0a                       PAYLOAD_LENGTH: 10 (1 byte, SR)
+  000003  01                       ID_LENGTH: 1
+  000004  61 2f 62                 TYPE: "a/b"
+  000007  69                       ID: "i"
+  000008  30 31 32 33 34 35 36 37  PAYLOAD: 10 bytes
+  000010  38 39
+`
+	if d := NewMessageFromRecords(r).Dump(); d != expected {
+		t.Error("unexpected dump:\n" + d)
+	}
+
+	long := NewRecord(Unknown, "", "", generic.New([]byte{0}))
+	long.chunks[0].SR = false
+	expected = `chunk 0 (record 0)
+  000000  c5                       FLAGS: MB=1 ME=1 CF=0 SR=0 IL=0 TNF=5 (Unknown)
+  000001  00                       TYPE_LENGTH: 0
+  000002  00 00 00 01              PAYLOAD_LENGTH: 1 (4 bytes)
+  000006  00                       PAYLOAD: 1 bytes
+`
+	if d := long.Dump(); d != expected {
+		t.Error("unexpected dump:\n" + d)
+	}
+}
+
+func TestDumpErrors(t *testing.T) {
+	buf, err := NewMessageFromRecords(
+		NewURIRecord("http://a.b"),
+		NewTextRecord("hello", "en"),
+	).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := Dump(buf[:len(buf)-2])
+	expected := `  00000c  02 65 6e 68 65 6c        PAYLOAD: truncated, 6 of 8 bytes
+  !! NDEF: unexpected end of data (record 1, chunk 0, PAYLOAD, offset 12)
+`
+	if !strings.HasSuffix(d, expected) {
+		t.Error("unexpected dump:\n" + d)
+	}
+
+	buf[0] &^= 0x80 // clear MB
+	d = Dump(buf)
+	expected = `  000000  11                       FLAGS: MB=0 ME=0 CF=0 SR=1 IL=0 TNF=1 (NFC Forum Well-Known Type)
+  !! NDEF Message Check: first record has not the MessageBegin flag set (record 0, chunk 0, FLAGS, offset 0)
+  000001  01                       TYPE_LENGTH: 1
+`
+	if !strings.Contains(d, expected) {
+		t.Error("unexpected dump:\n" + d)
+	}
+	if !strings.Contains(d, "chunk 0 (record 1)") {
+		t.Error("the dump should continue after the error")
+	}
+
+	d = Dump([]byte{0xd1, 0x01})
+	expected = `chunk 0 (record 0)
+  000000  d1                       FLAGS: MB=1 ME=1 CF=0 SR=1 IL=0 TNF=1 (NFC Forum Well-Known Type)
+  000001  01                       truncated header
+  !! NDEF: unexpected end of data (record 0, chunk 0, PAYLOAD_LENGTH, offset 2)
+`
+	if d != expected {
+		t.Error("unexpected dump:\n" + d)
+	}
+}
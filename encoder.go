@@ -0,0 +1,217 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bufio"
+	"io"
+)
+
+// DefaultChunkSize is the maximum payload length of the chunks written by
+// an Encoder for payloads of unknown length, unless otherwise specified.
+const DefaultChunkSize = 65536
+
+// Encoder writes NDEF Messages to an output stream.
+//
+// Besides whole Messages, an Encoder can write the records of a Message
+// one by one, taking care of setting the MB and ME flags as it goes.
+// Record payloads can be read from an io.Reader, in which case they are
+// copied to the output without holding them in memory.
+type Encoder struct {
+	w io.Writer
+
+	// MaxChunkSize is the maximum payload length of the chunks written.
+	// Longer payloads are written as chunked records. When 0, Records
+	// are written with the chunks they have and payloads read from an
+	// io.Reader use DefaultChunkSize.
+	MaxChunkSize int
+
+	// set after writing a record which is not the last in the message.
+	inMessage bool
+}
+
+// NewEncoder returns a new Encoder that writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{
+		w: w,
+	}
+}
+
+// Encode writes a full NDEF Message to the output.
+func (e *Encoder) Encode(m *Message) error {
+	if e.inMessage {
+		return ErrMessageInProgress
+	}
+	err := m.check()
+	if err != nil {
+		return err
+	}
+	last := len(m.Records) - 1
+	for i, r := range m.Records {
+		if err := e.EncodeRecord(r, i == last); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EncodeRecord writes a Record to the output. The MB flag is set if this
+// is the first Record written since the last Message was completed, and
+// the ME flag is set when last is true, completing the Message.
+//
+// The flags of the given Record are not modified.
+func (e *Encoder) EncodeRecord(r *Record, last bool) error {
+	err := r.check()
+	if err != nil {
+		return err
+	}
+	chunks, err := r.rechunk(e.MaxChunkSize)
+	if err != nil {
+		return err
+	}
+	lastChunk := len(chunks) - 1
+	for i, c := range chunks {
+		chunk := *c
+		chunk.MB = i == 0 && !e.inMessage
+		chunk.ME = i == lastChunk && last
+		chunkBytes, err := chunk.Marshal()
+		if err != nil {
+			return err
+		}
+		if _, err := e.w.Write(chunkBytes); err != nil {
+			return err
+		}
+	}
+	e.inMessage = !last
+	return nil
+}
+
+// EncodeReader writes a Record with the given TNF, Type and ID whose
+// payload is read from r. MB and ME flags are handled as in EncodeRecord.
+//
+// When length is known, exactly length bytes are copied from r. When
+// length is negative, r is read until io.EOF. Payloads are written as
+// chunked records with chunks of up to MaxChunkSize bytes (or
+// DefaultChunkSize) when they are longer than that, or their length is
+// not known.
+//
+// A chunk is only written once its payload has been read. If r ends
+// before length bytes, io.ErrUnexpectedEOF is returned: nothing is
+// written when the payload fits in the first chunk, and otherwise the
+// record is terminated with the bytes read, so that the output is
+// still well-formed.
+func (e *Encoder) EncodeReader(tnf TNF, typ, id string, r io.Reader, length int64, last bool) error {
+	if len(typ) > 255 {
+		return newParseError(ErrTypeTooLong, FieldType)
+	}
+	if len(id) > 255 {
+		return newParseError(ErrIDTooLong, FieldID)
+	}
+
+	chunkSize := e.MaxChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	var lr *io.LimitedReader
+	if length >= 0 {
+		if length < int64(chunkSize) {
+			chunkSize = int(length)
+		}
+		lr = &io.LimitedReader{R: r, N: length}
+		r = lr
+	}
+	br := bufio.NewReader(r)
+	buf := make([]byte, chunkSize)
+	first := true
+	for {
+		n, err := io.ReadFull(br, buf)
+		final := false
+		switch err {
+		case nil:
+			_, err = br.Peek(1)
+			if err == io.EOF {
+				final = true
+			} else if err != nil {
+				return err
+			}
+		case io.EOF, io.ErrUnexpectedEOF:
+			final = true
+		default:
+			return err
+		}
+		short := final && lr != nil && lr.N > 0
+		if short && first {
+			return io.ErrUnexpectedEOF
+		}
+
+		if first {
+			err = e.writeChunk(tnf, typ, id, buf[:n], true,
+				last && final, !final)
+		} else {
+			err = e.writeChunk(Unchanged, "", "", buf[:n], false,
+				last && final, !final)
+		}
+		if err != nil {
+			return err
+		}
+		if short {
+			e.inMessage = !last
+			return io.ErrUnexpectedEOF
+		}
+		if final {
+			break
+		}
+		first = false
+	}
+	e.inMessage = !last
+	return nil
+}
+
+// EncodeMedia writes a Record with a Media type (per RFC-2046) whose
+// payload is read from r, as described in EncodeReader.
+func (e *Encoder) EncodeMedia(mimeType string, r io.Reader, length int64, last bool) error {
+	return e.EncodeReader(MediaType, mimeType, "", r, length, last)
+}
+
+// writeChunk writes a single chunk with the given payload. The chunk is
+// the first one of a record when first is true.
+func (e *Encoder) writeChunk(tnf TNF, typ, id string, payload []byte, first, me, cf bool) error {
+	chunk := &recordChunk{
+		MB:            first && !e.inMessage,
+		ME:            me,
+		CF:            cf,
+		SR:            len(payload) < 256,
+		IL:            len(id) > 0,
+		TNF:           tnf,
+		TypeLength:    byte(len(typ)),
+		Type:          typ,
+		IDLength:      byte(len(id)),
+		ID:            id,
+		PayloadLength: uint64(len(payload)),
+	}
+	err := chunk.Check()
+	if err != nil {
+		return err
+	}
+
+	if _, err := e.w.Write(chunk.appendHeader(nil)); err != nil {
+		return err
+	}
+	_, err = e.w.Write(payload)
+	return err
+}
@@ -0,0 +1,178 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/hsanjuan/go-ndef/types/generic"
+)
+
+func ExampleEncoder() {
+	var out bytes.Buffer
+	enc := NewEncoder(&out)
+	enc.EncodeRecord(NewTextRecord("A picture", "en"), false)
+	enc.EncodeMedia("image/png", strings.NewReader("not really a png"), -1, true)
+
+	m := &Message{}
+	m.Unmarshal(out.Bytes())
+	fmt.Println(m)
+	// Output:
+	// urn:nfc:wkt:T:A picture
+	// image/png:<The message contains a payload>
+}
+
+func TestEncoderEncode(t *testing.T) {
+	m := NewMessageFromRecords(
+		NewTextRecord("abc", "en"),
+		NewURIRecord("http://a.b"),
+	)
+	expected, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	enc := NewEncoder(&out)
+	if err := enc.Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Error("Encode should produce the same bytes as Marshal")
+	}
+
+	// Record by record, with the flags of the original records unset.
+	out.Reset()
+	r1 := NewTextRecord("abc", "en")
+	r2 := NewURIRecord("http://a.b")
+	if err := enc.EncodeRecord(r1, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(m); err != ErrMessageInProgress {
+		t.Error("should not be able to encode a message now:", err)
+	}
+	if err := enc.EncodeRecord(r2, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Error("EncodeRecord should set the MB and ME flags")
+	}
+	if !r1.ME() || !r2.MB() {
+		t.Error("the given records should not be modified")
+	}
+}
+
+func TestEncoderEncodeReader(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 100)
+
+	var out bytes.Buffer
+	enc := NewEncoder(&out)
+	err := enc.EncodeReader(MediaType, "text/plain", "#a",
+		bytes.NewReader(payload), int64(len(payload)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.MaxChunkSize = 300
+	err = enc.EncodeReader(MediaType, "text/plain", "",
+		bytes.NewReader(payload), int64(len(payload)), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = enc.EncodeReader(MediaType, "text/plain", "",
+		iotest.HalfReader(bytes.NewReader(payload)), -1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Message{}
+	_, err = m.Unmarshal(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Records) != 3 {
+		t.Fatal("expected three records")
+	}
+	if len(m.Records[0].chunks) != 1 || m.Records[0].ID() != "#a" {
+		t.Error("a known-length payload should be written in one chunk")
+	}
+	for _, r := range m.Records[1:] {
+		if n := len(r.chunks); n != 4 {
+			t.Error("expected 4 chunks but got", n)
+		}
+	}
+	for _, r := range m.Records {
+		pl, err := r.Payload()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pl.Marshal(), payload) {
+			t.Error("unexpected payload")
+		}
+	}
+
+	// Small payloads of unknown length are not chunked.
+	out.Reset()
+	err = enc.EncodeReader(Unknown, "", "", strings.NewReader("abc"), -1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := NewMessage(Unknown, "", "", generic.New([]byte("abc"))).Marshal()
+	if !bytes.Equal(out.Bytes(), expected) {
+		t.Errorf("unexpected bytes: % 02x", out.Bytes())
+	}
+
+	longType := strings.Repeat("a", 256)
+	err = enc.EncodeReader(MediaType, longType, "", strings.NewReader("abc"), 3, true)
+	if !errors.Is(err, ErrTypeTooLong) {
+		t.Error("expected ErrTypeTooLong, got", err)
+	}
+
+	// Readers shorter than the declared length
+	out.Reset()
+	err = enc.EncodeReader(Unknown, "", "", strings.NewReader("abc"), 4, true)
+	if err != io.ErrUnexpectedEOF {
+		t.Error("expected ErrUnexpectedEOF, got", err)
+	}
+	err = enc.EncodeReader(Unknown, "", "", strings.NewReader("abc"), 400, true)
+	if err != io.ErrUnexpectedEOF {
+		t.Error("expected ErrUnexpectedEOF, got", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("nothing should have been written: % 02x", out.Bytes())
+	}
+
+	// Once chunks have been written, the record is terminated.
+	enc.MaxChunkSize = 2
+	err = enc.EncodeReader(Unknown, "", "", strings.NewReader("abc"), 5, true)
+	if err != io.ErrUnexpectedEOF {
+		t.Error("expected ErrUnexpectedEOF, got", err)
+	}
+	m = &Message{}
+	if _, err := m.Unmarshal(out.Bytes()); err != nil {
+		t.Fatal("the output should be well-formed:", err)
+	}
+	if !bytes.Equal(m.Records[0].payloadBytes(), []byte("abc")) || len(m.Records[0].chunks) != 2 {
+		t.Error("expected the bytes read in two chunks")
+	}
+}
@@ -0,0 +1,123 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"encoding"
+	"encoding/hex"
+)
+
+// Messages and Records implement the standard encoding interfaces. The
+// binary form is the NDEF representation and the text form is its
+// hexadecimal encoding, in lowercase.
+var (
+	_ encoding.BinaryMarshaler   = (*Message)(nil)
+	_ encoding.BinaryUnmarshaler = (*Message)(nil)
+	_ encoding.TextMarshaler     = (*Message)(nil)
+	_ encoding.TextUnmarshaler   = (*Message)(nil)
+	_ encoding.BinaryMarshaler   = (*Record)(nil)
+	_ encoding.BinaryUnmarshaler = (*Record)(nil)
+	_ encoding.TextMarshaler     = (*Record)(nil)
+	_ encoding.TextUnmarshaler   = (*Record)(nil)
+)
+
+// MarshalBinary returns the NDEF representation of the Message, as
+// Marshal does.
+func (m *Message) MarshalBinary() ([]byte, error) {
+	return m.Marshal()
+}
+
+// UnmarshalBinary parses a Message, as Unmarshal does, but fails with
+// ErrTrailingData when the data does not end with the Message.
+func (m *Message) UnmarshalBinary(data []byte) error {
+	n, err := m.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	return checkTrailingData(data, n)
+}
+
+// MarshalText returns the hexadecimal encoding of the Message.
+func (m *Message) MarshalText() ([]byte, error) {
+	return marshalHex(m.Marshal())
+}
+
+// UnmarshalText parses the hexadecimal encoding of a Message, in upper
+// or lower case. See UnmarshalBinary.
+func (m *Message) UnmarshalText(text []byte) error {
+	data, err := unmarshalHex(text)
+	if err != nil {
+		return err
+	}
+	return m.UnmarshalBinary(data)
+}
+
+// MarshalBinary returns the NDEF representation of the Record, as
+// Marshal does.
+func (r *Record) MarshalBinary() ([]byte, error) {
+	return r.Marshal()
+}
+
+// UnmarshalBinary parses a Record, as Unmarshal does, but fails with
+// ErrTrailingData when the data does not end with the Record.
+func (r *Record) UnmarshalBinary(data []byte) error {
+	n, err := r.Unmarshal(data)
+	if err != nil {
+		return err
+	}
+	return checkTrailingData(data, n)
+}
+
+// MarshalText returns the hexadecimal encoding of the Record.
+func (r *Record) MarshalText() ([]byte, error) {
+	return marshalHex(r.Marshal())
+}
+
+// UnmarshalText parses the hexadecimal encoding of a Record, in upper
+// or lower case. See UnmarshalBinary.
+func (r *Record) UnmarshalText(text []byte) error {
+	data, err := unmarshalHex(text)
+	if err != nil {
+		return err
+	}
+	return r.UnmarshalBinary(data)
+}
+
+func checkTrailingData(data []byte, n int) error {
+	if n < len(data) {
+		perr := newParseError(ErrTrailingData, "")
+		perr.Offset = int64(n)
+		return perr
+	}
+	return nil
+}
+
+func marshalHex(data []byte, err error) ([]byte, error) {
+	if err != nil {
+		return nil, err
+	}
+	text := make([]byte, hex.EncodedLen(len(data)))
+	hex.Encode(text, data)
+	return text, nil
+}
+
+func unmarshalHex(text []byte) ([]byte, error) {
+	data := make([]byte, hex.DecodedLen(len(text)))
+	_, err := hex.Decode(data, text)
+	return data, err
+}
@@ -0,0 +1,99 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+)
+
+func TestMessageEncodingInterfaces(t *testing.T) {
+	m := NewTextMessage("hi", "en")
+	bin, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := m.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(text) != "d101055402656e6869" {
+		t.Error("unexpected text encoding:", string(text))
+	}
+
+	m2 := &Message{}
+	if err := m2.UnmarshalBinary(bin); err != nil {
+		t.Fatal(err)
+	}
+	if m2.String() != m.String() {
+		t.Error("bad binary round trip")
+	}
+	m2 = &Message{}
+	if err := m2.UnmarshalText(bytes.ToUpper(text)); err != nil {
+		t.Fatal(err)
+	}
+	if m2.String() != m.String() {
+		t.Error("bad text round trip")
+	}
+
+	err = m2.UnmarshalBinary(append(bin, 0x00))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Err != ErrTrailingData ||
+		perr.Offset != int64(len(bin)) {
+		t.Error("expected ErrTrailingData, got", err)
+	}
+	if err := m2.UnmarshalText([]byte("d1z")); err == nil {
+		t.Error("expected an error for bad hex")
+	}
+}
+
+func TestRecordEncodingInterfaces(t *testing.T) {
+	r := NewURIRecord("https://example.com")
+	text, err := r.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := &Record{}
+	if err := r2.UnmarshalText(text); err != nil {
+		t.Fatal(err)
+	}
+	if r2.String() != r.String() {
+		t.Error("bad text round trip")
+	}
+	bin, _ := r.MarshalBinary()
+	if err := r2.UnmarshalBinary(append(bin, bin...)); !errors.Is(err, ErrTrailingData) {
+		t.Error("expected ErrTrailingData, got", err)
+	}
+}
+
+func TestMessageGob(t *testing.T) {
+	m := NewURIMessage("https://example.com")
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(m); err != nil {
+		t.Fatal(err)
+	}
+	m2 := &Message{}
+	if err := gob.NewDecoder(&buf).Decode(m2); err != nil {
+		t.Fatal(err)
+	}
+	if m2.String() != m.String() {
+		t.Error("bad gob round trip")
+	}
+}
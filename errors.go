@@ -0,0 +1,160 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors found when parsing or checking NDEF Messages, Records and their
+// chunks. They are wrapped in a *ParseError, which provides their location,
+// and can be checked with errors.Is().
+var (
+	ErrTruncated = errors.New("NDEF: unexpected end of data")
+
+	ErrNoRecords       = errors.New("NDEF Message Check: No records")
+	ErrNoMessageBegin  = errors.New("NDEF Message Check: first record has not the MessageBegin flag set")
+	ErrNoMessageEnd    = errors.New("NDEF Message Check: last record has not the MessageEnd flag set")
+	ErrBadMessageBegin = errors.New("NDEF Message Check: middle record has the MessageBegin flag set")
+	ErrBadMessageEnd   = errors.New("NDEF Message Check: middle record has the MessageEnd flag set")
+
+	ErrNoChunks         = errors.New("NDEF Record Check: No chunks")
+	ErrSingleChunked    = errors.New("NDEF Record Check: A single record cannot have the Chunk flag set")
+	ErrLastChunked      = errors.New("NDEF Record Check: Last record cannot have the Chunk flag set")
+	ErrMissingChunkFlag = errors.New("NDEF Record Check: Chunk Flag missing from some records")
+	ErrBadIL            = errors.New("NDEF Record Check: IL flag is set on a middle or final chunk")
+	ErrBadTypeLength    = errors.New("NDEF Record Check: A middle or last chunk has TypeLength != 0")
+	ErrBadTNF           = errors.New("NDEF Record Check: A middle or last chunk TNF is not UNCHANGED")
+
+	ErrNotEmpty       = errors.New("NDEF Chunk Check: Empty record TNF but not empty fields")
+	ErrTypeNotAllowed = errors.New("NDEF Chunk Check: This TNF does not support a Type field")
+	ErrReservedTNF    = errors.New("NDEF Chunk Check: The TNF cannot be Reserved, that value is reserved")
+	ErrNonASCIIType   = errors.New("NDEF Chunk Check: Record type names SHALL be formed of characters from of the US ASCII [ASCII] character set")
+	ErrNonASCIIID     = errors.New("NDEF Chunk Check: ID must use ASCII characters")
+	ErrTypeTooLong    = errors.New("NDEF Chunk Check: Type cannot be longer than 255 bytes")
+	ErrIDTooLong      = errors.New("NDEF Chunk Check: ID cannot be longer than 255 bytes")
+	ErrPayloadTooLong = errors.New("NDEF Chunk Check: Payload is too long for the PAYLOAD_LENGTH field")
+	ErrBadShortRecord = errors.New("NDEF Chunk Check: SR flag is set but PAYLOAD_LENGTH is 4 bytes long")
+)
+
+// Errors returned by CheckType, and when building or marshaling records
+// whose type is checked, for types which do not follow the syntax
+// required by their TNF. Records are decoded regardless of them.
+var (
+	ErrBadExternalType    = errors.New("NDEF Type Check: NFC Forum External type names must have the form <domain>:<name>")
+	ErrBadMediaType       = errors.New("NDEF Type Check: Media types must follow RFC 2046")
+	ErrBadAbsoluteURIType = errors.New("NDEF Type Check: Absolute URI types must be absolute URIs (RFC 3986)")
+)
+
+// ErrTrailingData is returned by UnmarshalBinary and UnmarshalText when
+// the data continues after the end of the Message or Record.
+var ErrTrailingData = errors.New("NDEF: unexpected data after the end")
+
+// ErrLimitExceeded is matched by the errors returned when decoding stops
+// because some of the Limits has been reached. See LimitError.
+var ErrLimitExceeded = errors.New("NDEF: decoding limit exceeded")
+
+// ErrInvalidTNF is returned by Record.SetTNF for values which are not a
+// valid TNF for a record.
+var ErrInvalidTNF = errors.New("NDEF Record: TNF must be a 3-bit value other than Unchanged")
+
+// ErrMessageInProgress is returned by Encoder.Encode when the last message
+// written record by record has not been completed.
+var ErrMessageInProgress = errors.New("NDEF Encoder: the last message has not been completed")
+
+// ErrBadChunkSize is returned when records are chunked with a maximum
+// chunk size which is not positive or does not fit in PAYLOAD_LENGTH.
+var ErrBadChunkSize = errors.New("NDEF Record: the maximum chunk size must be between 1 and 2^32-1")
+
+// Names of the fields of a record chunk, as used in ParseError.
+const (
+	FieldFlags         = "FLAGS" // MB, ME, CF, SR, IL and TNF
+	FieldTypeLength    = "TYPE_LENGTH"
+	FieldPayloadLength = "PAYLOAD_LENGTH"
+	FieldIDLength      = "ID_LENGTH"
+	FieldType          = "TYPE"
+	FieldID            = "ID"
+	FieldPayload       = "PAYLOAD"
+)
+
+// ParseError provides information about a problem found when parsing or
+// checking NDEF data. Err is one of the Err* values in this package.
+//
+// Position fields are set to -1 when they are not known or do not
+// apply, i.e. Offset is only known when parsing bytes.
+type ParseError struct {
+	Offset int64  // Offset of the offending field from the start of the input
+	Record int    // Index of the record in the message
+	Chunk  int    // Index of the chunk in the record
+	Field  string // Name of the offending field (FieldFlags...)
+	Err    error
+}
+
+func newParseError(err error, field string) *ParseError {
+	return &ParseError{
+		Offset: -1,
+		Record: -1,
+		Chunk:  -1,
+		Field:  field,
+		Err:    err,
+	}
+}
+
+// Error returns the message of the underlying error followed by
+// the known position information.
+func (e *ParseError) Error() string {
+	var pos []string
+	if e.Record >= 0 {
+		pos = append(pos, fmt.Sprintf("record %d", e.Record))
+	}
+	if e.Chunk >= 0 {
+		pos = append(pos, fmt.Sprintf("chunk %d", e.Chunk))
+	}
+	if e.Field != "" {
+		pos = append(pos, e.Field)
+	}
+	if e.Offset >= 0 {
+		pos = append(pos, fmt.Sprintf("offset %d", e.Offset))
+	}
+	if len(pos) == 0 {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s (%s)", e.Err, strings.Join(pos, ", "))
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// setIndex sets the record and chunk indexes of err when it is a
+// *ParseError and they are not set already. Negative values are ignored.
+func setIndex(err error, record, chunk int) error {
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		if perr.Record < 0 {
+			perr.Record = record
+		}
+		if perr.Chunk < 0 {
+			perr.Chunk = chunk
+		}
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrorLocation(t *testing.T) {
+	uriMsg, _ := NewURIMessage("http://s.com").Marshal() // d1 01 06 55 03 ...
+	twoRecords, _ := NewMessageFromRecords(
+		NewURIRecord("http://s.com"),
+		NewURIRecord("http://s.com"),
+	).Marshal()
+	reserved := append([]byte{}, twoRecords...)
+	reserved[10] |= byte(Reserved)
+
+	badChunk := []byte{
+		0xb1, 0x01, 0x01, 'U', 0x03, // MB, CF, SR, TNF=1
+		0x51, 0x00, 0x01, 'a', // ME, SR, TNF=1 (should be Unchanged)
+	}
+
+	type tcase struct {
+		input  []byte
+		err    error
+		record int
+		chunk  int
+		field  string
+		offset int64
+	}
+	tcs := map[string]tcase{
+		"empty":          {[]byte{}, ErrNoRecords, -1, -1, "", -1},
+		"type_length":    {[]byte{0x91}, ErrTruncated, 0, 0, FieldTypeLength, 1},
+		"payload_length": {[]byte{0x81, 0x01, 0x00}, ErrTruncated, 0, 0, FieldPayloadLength, 2},
+		"id_length":      {[]byte{0x99, 0x01, 0x03}, ErrTruncated, 0, 0, FieldIDLength, 3},
+		"type":           {uriMsg[:3], ErrTruncated, 0, 0, FieldType, 3},
+		"payload":        {uriMsg[:6], ErrTruncated, 0, 0, FieldPayload, 4},
+		"second_record":  {twoRecords[:13], ErrTruncated, 1, 0, FieldType, 13},
+		"reserved_tnf":   {reserved, ErrReservedTNF, 1, 0, FieldFlags, 10},
+		"no_me":          {twoRecords[:10], ErrNoMessageEnd, 0, 0, FieldFlags, 0},
+		"bad_chunk_tnf":  {badChunk, ErrBadTNF, 0, 1, FieldFlags, 5},
+	}
+
+	for name, tc := range tcs {
+		_, err := new(Message).Unmarshal(tc.input)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %s but got %s", name, tc.err, err)
+			continue
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: expected a *ParseError", name)
+			continue
+		}
+		if perr.Record != tc.record || perr.Chunk != tc.chunk ||
+			perr.Field != tc.field || perr.Offset != tc.offset {
+			t.Errorf("%s: unexpected location: %s", name, perr)
+		}
+		t.Log(err)
+	}
+}
+
+func TestParseErrorCheck(t *testing.T) {
+	m := &Message{Records: []*Record{NewURIRecord("http://s.com")}}
+	m.Records[0].SetMB(false)
+	_, err := m.Marshal()
+	var perr *ParseError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrNoMessageBegin) {
+		t.Fatal("expected a ParseError:", err)
+	}
+	if perr.Offset != -1 || perr.Record != 0 {
+		t.Error("unexpected location:", perr)
+	}
+	if perr.Error() != ErrNoMessageBegin.Error()+" (record 0, chunk 0, FLAGS)" {
+		t.Error("unexpected message:", perr)
+	}
+}
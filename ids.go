@@ -0,0 +1,125 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Errors returned by the IDIndex. ErrDuplicateID and ErrBadID are wrapped
+// in a *ParseError locating the offending record.
+var (
+	ErrDuplicateID       = errors.New("NDEF Message Check: several records have the same ID")
+	ErrBadID             = errors.New("NDEF Message Check: record ID is not a valid URI reference")
+	ErrDanglingReference = errors.New("NDEF Message Check: reference to a record ID which does not exist")
+)
+
+// An IDIndex maps the IDs of the records of a Message to the records.
+//
+// Record IDs are URI references (RFC 3986), which are resolved against
+// a base URI, so that relative and absolute references to the same
+// record match. Only the records of the Message are indexed, and not
+// those in nested messages, which are a different context.
+type IDIndex struct {
+	base    *url.URL
+	records map[string]*Record
+}
+
+// NewIDIndex returns an index of the IDs of the records in the Message,
+// resolved against the given base URI. When the base is empty, IDs are
+// not resolved, and only match references to the same URI. Records
+// without ID are not indexed.
+//
+// An error is returned if the base is not a valid URI, or if any ID is
+// not a valid URI reference or resolves to the same URI as another one.
+func NewIDIndex(m *Message, base string) (*IDIndex, error) {
+	var baseURL *url.URL
+	if base != "" {
+		var err error
+		baseURL, err = url.Parse(base)
+		if err != nil {
+			return nil, err
+		}
+	}
+	idx := &IDIndex{
+		base:    baseURL,
+		records: make(map[string]*Record),
+	}
+	for i, r := range m.Records {
+		if r.ID() == "" {
+			continue
+		}
+		id, err := idx.Resolve(r.ID())
+		if err != nil {
+			return nil, idError(ErrBadID, i)
+		}
+		if _, ok := idx.records[id]; ok {
+			return nil, idError(ErrDuplicateID, i)
+		}
+		idx.records[id] = r
+	}
+	return idx, nil
+}
+
+func idError(err error, record int) *ParseError {
+	perr := newParseError(err, FieldID)
+	perr.Record = record
+	perr.Chunk = 0
+	return perr
+}
+
+// Resolve returns the given reference resolved against the base URI of
+// the index, as it is used to look up records. Without base URI, the
+// reference is returned as parsed.
+func (idx *IDIndex) Resolve(ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if idx.base == nil {
+		return u.String(), nil
+	}
+	return idx.base.ResolveReference(u).String(), nil
+}
+
+// Lookup returns the record referenced by ref, or nil if there is none.
+func (idx *IDIndex) Lookup(ref string) *Record {
+	id, err := idx.Resolve(ref)
+	if err != nil {
+		return nil
+	}
+	return idx.records[id]
+}
+
+// Len returns the number of records in the index.
+func (idx *IDIndex) Len() int {
+	return len(idx.records)
+}
+
+// CheckReferences returns an error matching ErrDanglingReference for the
+// first of the given references which does not resolve to a record.
+func (idx *IDIndex) CheckReferences(refs ...string) error {
+	for _, ref := range refs {
+		if idx.Lookup(ref) == nil {
+			return fmt.Errorf("%w: %q", ErrDanglingReference, ref)
+		}
+	}
+	return nil
+}
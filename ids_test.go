@@ -0,0 +1,102 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"testing"
+)
+
+func idTestMessage(t *testing.T, ids ...string) *Message {
+	var records []*Record
+	for _, id := range ids {
+		r := NewTextRecord(id, "en")
+		if err := r.SetID(id); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, r)
+	}
+	return NewMessageFromRecords(records...)
+}
+
+func TestIDIndex(t *testing.T) {
+	m := idTestMessage(t, "0", "urn:nfc:x", "", "http://example.com/a/b")
+	idx, err := NewIDIndex(m, "http://example.com/a/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Len() != 3 {
+		t.Error("expected 3 records but got", idx.Len())
+	}
+
+	tcs := map[string]*Record{
+		"0":                      m.Records[0],
+		"http://example.com/a/0": m.Records[0],
+		"urn:nfc:x":              m.Records[1],
+		"b":                      m.Records[3],
+		"../a/b":                 m.Records[3],
+		"c":                      nil,
+		"":                       nil,
+	}
+	for ref, r := range tcs {
+		if idx.Lookup(ref) != r {
+			t.Errorf("%q resolved to the wrong record", ref)
+		}
+	}
+
+	if err := idx.CheckReferences("0", "b"); err != nil {
+		t.Error(err)
+	}
+	err = idx.CheckReferences("0", "c")
+	if !errors.Is(err, ErrDanglingReference) {
+		t.Error("expected a dangling reference error:", err)
+	}
+}
+
+func TestIDIndexNoBase(t *testing.T) {
+	m := idTestMessage(t, "a", "/a", "./a")
+	idx, err := NewIDIndex(m, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idx.Len() != 3 {
+		t.Error("expected 3 records but got", idx.Len())
+	}
+	for i, ref := range []string{"a", "/a", "./a"} {
+		if idx.Lookup(ref) != m.Records[i] {
+			t.Errorf("%q resolved to the wrong record", ref)
+		}
+	}
+}
+
+func TestIDIndexErrors(t *testing.T) {
+	_, err := NewIDIndex(idTestMessage(t, "a", "b", "http://x/a"), "http://x/")
+	var perr *ParseError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrDuplicateID) || perr.Record != 2 {
+		t.Error("expected a duplicate ID in record 2:", err)
+	}
+
+	_, err = NewIDIndex(idTestMessage(t, "a", "%zz"), "")
+	if !errors.As(err, &perr) || !errors.Is(err, ErrBadID) || perr.Record != 1 {
+		t.Error("expected a bad ID in record 1:", err)
+	}
+
+	if _, err = NewIDIndex(idTestMessage(t, "a"), "%zz"); err == nil {
+		t.Error("expected an error for a bad base")
+	}
+}
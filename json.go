@@ -0,0 +1,244 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/hsanjuan/go-ndef/types/generic"
+	"github.com/hsanjuan/go-ndef/types/wkt/text"
+	"github.com/hsanjuan/go-ndef/types/wkt/uri"
+)
+
+// Messages and Records can be represented in JSON, following the schema
+// in message.schema.json:
+//
+//   {"records": [
+//     {"tnf": 1, "type": "T", "id": "a", "payload": {"language": "en", "text": "hi"}},
+//     {"tnf": 1, "type": "U", "payload": {"uri": "https://example.com"}},
+//     {"tnf": 1, "type": "Sp", "payload": {"records": [...]}},
+//     {"tnf": 2, "type": "image/png", "payload": "iVBORw0KGgo="}
+//   ]}
+//
+// Text, URI and Smart Poster payloads are represented by their decoded
+// structure, as long as it produces back the same bytes and its strings
+// are valid UTF-8. Otherwise, and for any other type, the payload is a
+// base64-encoded string. Likewise, types which are not valid UTF-8 are
+// given base64-encoded in "typeBase64", with an empty "type". IDs, which
+// are ASCII, cannot be represented otherwise.
+//
+// Converting a Message to JSON and back results in identical bytes, as
+// long as records are not chunked.
+
+// Errors returned when converting Messages and Records to and from JSON.
+var (
+	ErrJSONNullRecord = errors.New("NDEF JSON: records cannot be null")
+	ErrJSONPayload    = errors.New("NDEF JSON: the payload must be a base64 string for this record type")
+	ErrJSONType       = errors.New("NDEF JSON: type and typeBase64 cannot be both set")
+	ErrJSONID         = errors.New("NDEF JSON: the record ID is not valid UTF-8")
+)
+
+type jsonMessage struct {
+	Records []*Record `json:"records"`
+}
+
+type jsonRecord struct {
+	TNF        TNF             `json:"tnf"`
+	Type       string          `json:"type"`
+	TypeBase64 []byte          `json:"typeBase64,omitempty"`
+	ID         string          `json:"id,omitempty"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type jsonText struct {
+	Language string `json:"language"`
+	Text     string `json:"text"`
+}
+
+type jsonURI struct {
+	URI string `json:"uri"`
+}
+
+// MarshalJSON returns the JSON representation of the Message.
+func (m *Message) MarshalJSON() ([]byte, error) {
+	records := m.Records
+	if records == nil {
+		records = []*Record{}
+	}
+	return json.Marshal(jsonMessage{Records: records})
+}
+
+// UnmarshalJSON parses the JSON representation of a Message. The MB and
+// ME flags are set on the first and last records.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var jm jsonMessage
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return err
+	}
+	for _, r := range jm.Records {
+		if r == nil {
+			return ErrJSONNullRecord
+		}
+	}
+	msg := NewMessageFromRecords(jm.Records...)
+	if err := msg.check(); err != nil {
+		return err
+	}
+	m.Records = msg.Records
+	return nil
+}
+
+// MarshalJSON returns the JSON representation of the Record.
+func (r *Record) MarshalJSON() ([]byte, error) {
+	payload, err := r.jsonPayload()
+	if err != nil {
+		return nil, err
+	}
+	jr := jsonRecord{
+		TNF:     r.TNF(),
+		Type:    r.Type(),
+		ID:      r.ID(),
+		Payload: payload,
+	}
+	// encoding/json would replace invalid UTF-8.
+	if !utf8.ValidString(jr.Type) {
+		jr.TypeBase64 = []byte(jr.Type)
+		jr.Type = ""
+	}
+	if !utf8.ValidString(jr.ID) {
+		return nil, ErrJSONID
+	}
+	return json.Marshal(jr)
+}
+
+// jsonPayload returns the typed representation of the payload when
+// there is one which is lossless, or base64 otherwise.
+func (r *Record) jsonPayload() (json.RawMessage, error) {
+	payload := r.payloadBytes()
+
+	var typed interface{}
+	var typedBytes []byte
+	pl, err := r.Payload()
+	if err == nil {
+		switch pl := pl.(type) {
+		case *text.Payload:
+			if utf8.ValidString(pl.Language) && utf8.ValidString(pl.Text) {
+				typed = jsonText{Language: pl.Language, Text: pl.Text}
+				typedBytes = text.New(pl.Text, pl.Language).Marshal()
+			}
+		case *uri.Payload:
+			if utf8.ValidString(pl.String()) {
+				typed = jsonURI{URI: pl.String()}
+				typedBytes = uri.New(pl.String()).Marshal()
+			}
+		case *SmartPosterPayload:
+			typed = pl.Message
+			typedBytes, err = pl.Message.Marshal()
+		}
+	}
+
+	if err == nil && typed != nil && bytes.Equal(typedBytes, payload) {
+		return json.Marshal(typed)
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	return json.Marshal(payload)
+}
+
+// UnmarshalJSON parses the JSON representation of a Record. The
+// resulting Record is not chunked, and has the MB and ME flags set.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var jr jsonRecord
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return err
+	}
+
+	typ := jr.Type
+	if jr.TypeBase64 != nil {
+		if typ != "" {
+			return ErrJSONType
+		}
+		typ = string(jr.TypeBase64)
+	}
+
+	newR := &Record{}
+	if err := newR.SetTNF(jr.TNF); err != nil {
+		return err
+	}
+	if err := newR.SetType(typ); err != nil {
+		return err
+	}
+	if err := newR.SetID(jr.ID); err != nil {
+		return err
+	}
+
+	payload, err := jr.payload()
+	if err != nil {
+		return err
+	}
+	if err := newR.SetPayload(payload); err != nil {
+		return err
+	}
+	r.chunks = newR.chunks
+	r.opts = DecodeOptions{}
+	return nil
+}
+
+// payload returns the RecordPayload for the JSON payload, which is
+// either a base64 string or the typed representation for the record
+// type.
+func (jr *jsonRecord) payload() (RecordPayload, error) {
+	data := bytes.TrimSpace(jr.Payload)
+	if len(data) == 0 || data[0] != '{' {
+		var payload []byte
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return nil, err
+		}
+		return generic.New(payload), nil
+	}
+
+	if jr.TNF != NFCForumWellKnownType {
+		return nil, ErrJSONPayload
+	}
+	switch jr.Type {
+	case "T":
+		var jt jsonText
+		if err := json.Unmarshal(data, &jt); err != nil {
+			return nil, err
+		}
+		return text.New(jt.Text, jt.Language), nil
+	case "U":
+		var ju jsonURI
+		if err := json.Unmarshal(data, &ju); err != nil {
+			return nil, err
+		}
+		return uri.New(ju.URI), nil
+	case "Sp":
+		msg := &Message{}
+		if err := json.Unmarshal(data, msg); err != nil {
+			return nil, err
+		}
+		return NewSmartPosterPayload(msg), nil
+	default:
+		return nil, ErrJSONPayload
+	}
+}
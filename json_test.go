@@ -0,0 +1,167 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/generic"
+	"github.com/hsanjuan/go-ndef/types/wkt/uri"
+)
+
+func TestMessageJSON(t *testing.T) {
+	textRecord := NewTextRecord("hi", "en")
+	if err := textRecord.SetID("t"); err != nil {
+		t.Fatal(err)
+	}
+	m := NewMessageFromRecords(
+		textRecord,
+		NewSmartPosterRecord(NewURIMessage("https://example.com")),
+		NewMediaRecord("a/b", []byte{1, 2, 3}),
+		&Record{chunks: []*recordChunk{newChunk(Empty, "", "", nil)}},
+	)
+
+	js, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"records":[` +
+		`{"tnf":1,"type":"T","id":"t","payload":{"language":"en","text":"hi"}},` +
+		`{"tnf":1,"type":"Sp","payload":{"records":[{"tnf":1,"type":"U","payload":{"uri":"https://example.com"}}]}},` +
+		`{"tnf":2,"type":"a/b","payload":"AQID"},` +
+		`{"tnf":0,"type":"","payload":""}]}`
+	if string(js) != expected {
+		t.Error("unexpected JSON:", string(js))
+	}
+
+	m2 := &Message{}
+	if err := json.Unmarshal(js, m2); err != nil {
+		t.Fatal(err)
+	}
+	b1, _ := m.Marshal()
+	b2, err := m2.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Error("round trip should produce identical bytes")
+	}
+}
+
+func TestRecordJSONLossless(t *testing.T) {
+	// Not using the identifier code for the URI prefix.
+	u := &uri.Payload{IdentCode: 0, URIField: "https://example.com"}
+	r := NewRecord(NFCForumWellKnownType, "U", "", u)
+	// UTF-16 text.
+	tx := NewRecord(NFCForumWellKnownType, "T", "",
+		generic.New([]byte{0x82, 'e', 'n', 0x00, 'h'}))
+	// Malformed smart poster.
+	sp := NewRecord(NFCForumWellKnownType, "Sp", "",
+		generic.New([]byte{0x01}))
+
+	for _, r := range []*Record{r, tx, sp} {
+		js, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var jr jsonRecord
+		if err := json.Unmarshal(js, &jr); err != nil {
+			t.Fatal(err)
+		}
+		if jr.Payload[0] != '"' {
+			t.Error("expected a base64 payload:", string(js))
+		}
+
+		r2 := &Record{}
+		if err := json.Unmarshal(js, r2); err != nil {
+			t.Fatal(err)
+		}
+		b1, _ := r.Marshal()
+		b2, _ := r2.Marshal()
+		if !bytes.Equal(b1, b2) {
+			t.Error("round trip should produce identical bytes")
+		}
+	}
+}
+
+func TestRecordJSONInvalidUTF8(t *testing.T) {
+	tcs := []string{
+		"d101055402e96e6869", // Text record with language "\xe9n"
+		"d20301612fe978",     // Media record with type "a/\xe9"
+	}
+	for _, tc := range tcs {
+		rBytes, err := hex.DecodeString(tc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := &Record{}
+		if _, err := r.Unmarshal(rBytes); err != nil {
+			t.Fatal(err)
+		}
+		js, err := json.Marshal(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r2 := &Record{}
+		if err := json.Unmarshal(js, r2); err != nil {
+			t.Fatal(err)
+		}
+		b2, err := r2.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(rBytes, b2) {
+			t.Errorf("%s: round trip through %s produced %x", tc, js, b2)
+		}
+	}
+}
+
+func TestRecordJSONErrors(t *testing.T) {
+	tcs := []string{
+		`{"tnf":2,"type":"a/b","payload":{"text":"hi"}}`,
+		`{"tnf":1,"type":"X","payload":{"text":"hi"}}`,
+		`{"tnf":6,"type":"","payload":""}`,
+		`{"tnf":0,"type":"a","payload":""}`,
+		`{"tnf":1,"type":"T","payload":"not base64"}`,
+		`{"tnf":2,"type":"a/b","typeBase64":"YS9i","payload":""}`,
+	}
+	for _, tc := range tcs {
+		r := &Record{}
+		if err := json.Unmarshal([]byte(tc), r); err == nil {
+			t.Error("expected an error for", tc)
+		}
+	}
+
+	r := NewMediaRecord("a/b", nil)
+	r.chunks[0].ID = "\xe9"
+	if _, err := json.Marshal(r); !errors.Is(err, ErrJSONID) {
+		t.Error("expected an error for an ID which is not valid UTF-8:", err)
+	}
+
+	m := &Message{}
+	if err := json.Unmarshal([]byte(`{"records":[null]}`), m); err != ErrJSONNullRecord {
+		t.Error("expected an error for null records:", err)
+	}
+	if err := json.Unmarshal([]byte(`{"records":[]}`), m); err == nil {
+		t.Error("expected an error for a message without records")
+	}
+}
@@ -0,0 +1,104 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"fmt"
+)
+
+// Limits bound the resources which decoding can use, which is important
+// when handling untrusted data. A zero field means no limit.
+type Limits struct {
+	// MaxDepth is the maximum nesting level of messages inside
+	// payloads, i.e. a Smart Poster inside a Smart Poster. The records
+	// of a top-level message are at depth 0.
+	MaxDepth int
+	// MaxRecords is the maximum number of records in a message.
+	MaxRecords int
+	// MaxPayloadLength is the maximum length of the payload of a single
+	// record, adding up all its chunks.
+	MaxPayloadLength uint64
+	// MaxTotalPayloadLength is the maximum length of all the payloads
+	// in a message, adding up all its records.
+	MaxTotalPayloadLength uint64
+}
+
+// DefaultLimits are used when DecodeOptions do not set any. They only
+// limit the nesting depth: the number of records and the payload lengths
+// are not bounded, as byte slices are already in memory and the Decoder
+// does not allocate beyond the data it reads. Set the rest of the limits
+// when decoding untrusted streams.
+var DefaultLimits = Limits{
+	MaxDepth: 16,
+}
+
+// LimitError is the error carried by a ParseError when one of the
+// Limits is exceeded. It matches ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	// Limit is the name of the Limits field, i.e. "MaxRecords".
+	Limit string
+	// Max is the value of the limit.
+	Max uint64
+}
+
+// Error returns a description of the error.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s is %d", ErrLimitExceeded, e.Limit, e.Max)
+}
+
+// Is returns true for ErrLimitExceeded.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+func limitError(limit string, max uint64, field string) *ParseError {
+	return newParseError(&LimitError{Limit: limit, Max: max}, field)
+}
+
+// checkRecords returns an error when a message cannot have n records.
+func (l Limits) checkRecords(n int) error {
+	if l.MaxRecords > 0 && n > l.MaxRecords {
+		return limitError("MaxRecords", uint64(l.MaxRecords), FieldFlags)
+	}
+	return nil
+}
+
+// checkDepth returns an error when messages cannot be nested at depth.
+func (l Limits) checkDepth(depth int) error {
+	if l.MaxDepth > 0 && depth > l.MaxDepth {
+		return limitError("MaxDepth", uint64(l.MaxDepth), FieldPayload)
+	}
+	return nil
+}
+
+// checkPayload returns an error when a chunk with the given payload
+// length cannot be added to a record and message whose payloads already
+// add up to the given lengths.
+func (l Limits) checkPayload(record, total, length uint64) error {
+	if l.MaxPayloadLength > 0 && (length > l.MaxPayloadLength ||
+		record > l.MaxPayloadLength-length) {
+		return limitError("MaxPayloadLength", l.MaxPayloadLength,
+			FieldPayloadLength)
+	}
+	if l.MaxTotalPayloadLength > 0 && (length > l.MaxTotalPayloadLength ||
+		total > l.MaxTotalPayloadLength-length) {
+		return limitError("MaxTotalPayloadLength",
+			l.MaxTotalPayloadLength, FieldPayloadLength)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/generic"
+)
+
+func TestLimits(t *testing.T) {
+	chunked, err := NewChunkedRecord(MediaType, "a/b", "",
+		generic.New(make([]byte, 10)), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgWithChunks := NewMessageFromRecords(chunked)
+	msgWithRecords := NewMessageFromRecords(
+		NewMediaRecord("a/b", make([]byte, 6)),
+		NewMediaRecord("a/b", make([]byte, 6)),
+		NewMediaRecord("a/b", make([]byte, 6)),
+	)
+
+	tcs := []struct {
+		name   string
+		msg    *Message
+		limits Limits
+		limit  string
+		record int
+		chunk  int
+	}{
+		{"records", msgWithRecords, Limits{MaxRecords: 2}, "MaxRecords", 2, -1},
+		{"records_ok", msgWithRecords, Limits{MaxRecords: 3}, "", 0, 0},
+		{"payload", msgWithChunks, Limits{MaxPayloadLength: 9}, "MaxPayloadLength", 0, 2},
+		{"payload_ok", msgWithChunks, Limits{MaxPayloadLength: 10}, "", 0, 0},
+		{"total", msgWithRecords, Limits{MaxTotalPayloadLength: 17}, "MaxTotalPayloadLength", 2, 0},
+		{"total_ok", msgWithRecords, Limits{MaxTotalPayloadLength: 18}, "", 0, 0},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			buf, err := tc.msg.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			limits := tc.limits
+			opts := DecodeOptions{Limits: &limits, Lenient: true}
+			_, _, err = opts.Unmarshal(buf, &Message{})
+			if tc.limit == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			checkLimitError(t, err, tc.limit, tc.record, tc.chunk)
+
+			dec := NewDecoder(bytes.NewReader(buf))
+			dec.Options = opts
+			err = dec.Decode(&Message{})
+			checkLimitError(t, err, tc.limit, tc.record, tc.chunk)
+		})
+	}
+}
+
+func TestLimitsUnmarshalRecord(t *testing.T) {
+	r, err := NewChunkedRecord(MediaType, "a/b", "",
+		generic.New(make([]byte, 10)), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := DecodeOptions{Limits: &Limits{MaxPayloadLength: 9}}
+	_, _, err = opts.UnmarshalRecord(buf, &Record{})
+	checkLimitError(t, err, "MaxPayloadLength", -1, 2)
+
+	opts.Limits.MaxPayloadLength = 10
+	n, _, err := opts.UnmarshalRecord(buf, &Record{})
+	if err != nil || n != len(buf) {
+		t.Error("unexpected result:", n, err)
+	}
+}
+
+func checkLimitError(t *testing.T, err error, limit string, record, chunk int) {
+	t.Helper()
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatal("expected ErrLimitExceeded, got", err)
+	}
+	var lerr *LimitError
+	if !errors.As(err, &lerr) || lerr.Limit != limit {
+		t.Error("expected a LimitError for", limit, "got", err)
+	}
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Record != record || perr.Chunk != chunk {
+		t.Errorf("unexpected location: %s", err)
+	}
+}
+
+func TestLimitsMaxDepth(t *testing.T) {
+	msg := NewTextMessage("hi", "en")
+	for i := 0; i < 3; i++ {
+		msg = NewSmartPosterMessage(msg)
+	}
+	buf, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nesting returns the error found when decoding the innermost payload.
+	nesting := func(limits *Limits) error {
+		msg := &Message{}
+		_, _, err := DecodeOptions{Limits: limits}.Unmarshal(buf, msg)
+		for err == nil {
+			var pl RecordPayload
+			pl, err = msg.Records[0].Payload()
+			sp, ok := pl.(*SmartPosterPayload)
+			if !ok {
+				break
+			}
+			msg = sp.Message
+		}
+		return err
+	}
+
+	if err := nesting(&Limits{MaxDepth: 3}); err != nil {
+		t.Error(err)
+	}
+	err = nesting(&Limits{MaxDepth: 2})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Error("expected ErrLimitExceeded, got", err)
+	}
+
+	// a deeply nested message with the default limits
+	msg = NewTextMessage("hi", "en")
+	for i := 0; i < DefaultLimits.MaxDepth+1; i++ {
+		msg = NewSmartPosterMessage(msg)
+	}
+	if buf, err = msg.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := nesting(nil); !errors.Is(err, ErrLimitExceeded) {
+		t.Error("expected ErrLimitExceeded, got", err)
+	}
+}
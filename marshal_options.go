@@ -0,0 +1,72 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+)
+
+// MarshalOptions allows to configure how Messages and Records are
+// serialized.
+type MarshalOptions struct {
+	// MaxChunkSize is the maximum payload length of a record chunk.
+	// Records with longer payloads are split into several chunks.
+	// When 0, records are marshaled with the chunks they have.
+	MaxChunkSize int
+
+	// CheckTypes makes marshaling fail for records whose type does not
+	// follow the syntax required by their TNF. See CheckType.
+	CheckTypes bool
+}
+
+// Marshal returns the byte slice representation of a Message, as
+// Message.Marshal(), but following the options.
+func (o MarshalOptions) Marshal(m *Message) ([]byte, error) {
+	err := m.check()
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	for _, r := range m.Records {
+		rBytes, err := o.MarshalRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(rBytes)
+	}
+	return buffer.Bytes(), nil
+}
+
+// MarshalRecord returns the byte representation of a Record, as
+// Record.Marshal(), but following the options.
+func (o MarshalOptions) MarshalRecord(r *Record) ([]byte, error) {
+	if o.CheckTypes {
+		if err := r.checkType(); err != nil {
+			return nil, err
+		}
+	}
+	chunks, err := r.rechunk(o.MaxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	rechunked := &Record{
+		chunks: chunks,
+	}
+	return rechunked.Marshal()
+}
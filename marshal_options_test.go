@@ -0,0 +1,132 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/generic"
+)
+
+func TestMarshalOptions(t *testing.T) {
+	payload := bytes.Repeat([]byte("abc"), 100)
+	m := NewMessageFromRecords(
+		NewMediaRecord("text/plain", payload),
+		NewURIRecord("http://a.b"),
+	)
+	opts := MarshalOptions{MaxChunkSize: 64}
+	mBytes, err := opts.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m2 := &Message{}
+	_, err = m2.Unmarshal(mBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(m2.Records[0].chunks); n != 5 {
+		t.Error("expected 5 chunks but got", n)
+	}
+	if n := len(m2.Records[1].chunks); n != 1 {
+		t.Error("expected 1 chunk but got", n)
+	}
+	for _, c := range m2.Records[0].chunks {
+		if c.PayloadLength > 64 {
+			t.Error("chunk is too long")
+		}
+	}
+	if len(m.Records[0].chunks) != 1 {
+		t.Error("the original message should not be modified")
+	}
+
+	// Merge the chunks back
+	m2Bytes, err := MarshalOptions{MaxChunkSize: 1000}.Marshal(m2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := m.Marshal()
+	if !bytes.Equal(m2Bytes, expected) {
+		t.Error("should have produced a single-chunked record")
+	}
+
+	// Without options, chunks are kept.
+	m2Bytes, err = MarshalOptions{}.Marshal(m2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m2Bytes, mBytes) {
+		t.Error("the chunks should have been preserved")
+	}
+
+	_, err = MarshalOptions{MaxChunkSize: -1}.Marshal(m2)
+	if err != ErrBadChunkSize {
+		t.Error("expected an error for a bad chunk size:", err)
+	}
+}
+
+func TestNewChunkedRecord(t *testing.T) {
+	r, err := NewChunkedRecord(NFCForumExternalType, "test", "#a",
+		generic.New([]byte("abcdefg")), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.chunks) != 3 {
+		t.Fatal("expected 3 chunks")
+	}
+	first, middle, last := r.chunks[0], r.chunks[1], r.chunks[2]
+	if !first.CF || !middle.CF || last.CF {
+		t.Error("bad CF flags")
+	}
+	if middle.TNF != Unchanged || last.TNF != Unchanged ||
+		middle.TypeLength != 0 || middle.IL {
+		t.Error("middle and last chunks should not have type or ID")
+	}
+	if !r.MB() || !r.ME() || first.ME || last.MB {
+		t.Error("bad MB/ME flags")
+	}
+
+	rBytes, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := &Record{}
+	if _, err := r2.Unmarshal(rBytes); err != nil {
+		t.Fatal(err)
+	}
+	pl, _ := r2.Payload()
+	if !bytes.Equal(pl.Marshal(), []byte("abcdefg")) || r2.ID() != "#a" {
+		t.Error("unexpected record contents")
+	}
+
+	_, err = NewChunkedRecord(NFCForumExternalType, "test", "", nil, 0)
+	if err != ErrBadChunkSize {
+		t.Error("expected an error for a bad chunk size:", err)
+	}
+	_, err = NewChunkedRecord(Empty, "", "",
+		generic.New([]byte("abcdefg")), 3)
+	if err == nil {
+		t.Error("expected an error for a non-empty Empty record")
+	}
+	_, err = NewChunkedRecord(MediaType, string(make([]byte, 256)), "",
+		generic.New([]byte("abcdefg")), 3)
+	if err == nil {
+		t.Error("expected an error for a long type")
+	}
+}
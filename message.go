@@ -18,7 +18,6 @@
 package ndef
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"strings"
@@ -35,7 +34,7 @@ type Message struct {
 
 // NewMessage returns a new Message initialized with a single Record
 // with the TNF, Type, ID and Payload values.
-func NewMessage(tnf byte, rtype string, id string, payload RecordPayload) *Message {
+func NewMessage(tnf TNF, rtype string, id string, payload RecordPayload) *Message {
 	return &Message{
 		Records: []*Record{NewRecord(tnf, rtype, id, payload)},
 	}
@@ -153,25 +152,92 @@ func (m *Message) Inspect() string {
 // parsing all Records in the slice, until there are no more to parse.
 //
 // Returns the number of bytes processed (message length), or an error
-// if something looks wrong with the message or its records.
+// if something looks wrong with the message or its records. Use
+// DecodeOptions to parse messages leniently.
 func (m *Message) Unmarshal(buf []byte) (rLen int, err error) {
+	src := &sliceChunkSource{buf: buf}
+	err = m.readRecords(src, newDecodeState(DecodeOptions{}))
+	return src.off, err
+}
+
+// readRecords reads Records from src until one with the ME flag set
+// is read or there is no more data.
+func (m *Message) readRecords(src chunkSource, st *decodeState) error {
 	m.Records = []*Record{}
-	rLen = 0
-	for rLen < len(buf) {
+	st.totalPayload = 0
+	limits := st.opts.limits()
+	for {
+		more, err := src.more()
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+		if err := limits.checkRecords(len(m.Records) + 1); err != nil {
+			return setIndex(err, len(m.Records), -1)
+		}
+		st.record = len(m.Records)
+		mark := len(st.warnings)
 		r := new(Record)
-		recordLen, err := r.Unmarshal(buf[rLen:])
-		rLen += recordLen
+		err = r.readChunks(src, st)
 		if err != nil {
-			return rLen, err
+			err = setIndex(err, len(m.Records), -1)
+			// trailing garbage after the last record
+			if len(m.Records) > 0 && st.warn(err) {
+				// do not bother about anything else in it
+				warning := st.warnings[len(st.warnings)-1]
+				st.warnings = append(st.warnings[:mark], warning)
+				break
+			}
+			return err
+		}
+		if len(m.Records) > 0 && r.MB() && st.lenient() {
+			// This record starts a new message
+			src.unread(r.chunks, st.warnings[mark:])
+			st.warnings = st.warnings[:mark]
+			break
 		}
 		m.Records = append(m.Records, r)
 		if r.ME() { // last record in message
 			break
 		}
 	}
+	st.record = -1
+
+	err := m.locate(m.check())
+	for err != nil && st.lenient() {
+		// fix the flags until the message checks
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			return err
+		}
+		switch {
+		case errors.Is(err, ErrNoMessageBegin):
+			m.Records[0].SetMB(true)
+		case errors.Is(err, ErrNoMessageEnd):
+			m.Records[len(m.Records)-1].SetME(true)
+		case errors.Is(err, ErrBadMessageBegin):
+			m.Records[perr.Record].SetMB(false)
+		case errors.Is(err, ErrBadMessageEnd):
+			m.Records[perr.Record].SetME(false)
+		default:
+			return err
+		}
+		st.warn(err)
+		err = m.locate(m.check())
+	}
+	return err
+}
 
-	err = m.check()
-	return rLen, err
+// locate sets the offset of a *ParseError affecting a record of this
+// message.
+func (m *Message) locate(err error) error {
+	var perr *ParseError
+	if errors.As(err, &perr) && perr.Record >= 0 && perr.Record < len(m.Records) {
+		return m.Records[perr.Record].locate(err)
+	}
+	return err
 }
 
 // Marshal provides the byte slice representation of a Message,
@@ -179,57 +245,85 @@ func (m *Message) Unmarshal(buf []byte) (rLen int, err error) {
 //
 // Returns an error if something goes wrong.
 func (m *Message) Marshal() ([]byte, error) {
+	return m.AppendMarshal(nil)
+}
+
+// AppendMarshal appends the byte representation of a Message to dst and
+// returns the extended buffer, as Marshal does. It does not allocate when
+// dst has enough capacity. On error, dst is returned unmodified.
+func (m *Message) AppendMarshal(dst []byte) ([]byte, error) {
 	err := m.check()
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
 
-	var buffer bytes.Buffer
+	buf := dst
 	for _, r := range m.Records {
-		rBytes, err := r.Marshal()
+		buf, err = r.AppendMarshal(buf)
 		if err != nil {
-			return nil, err
+			return dst, err
 		}
-		_, err = buffer.Write(rBytes)
-		if err != nil {
-			return nil, err
+	}
+	return buf, nil
+}
+
+// EncodedLen returns the length of the Marshaling of a valid Message,
+// computed from the sizes of its records without marshaling it. See
+// MarshalOptions to marshal Messages with a different length.
+func (m *Message) EncodedLen() int {
+	l := 0
+	for _, r := range m.Records {
+		l += r.EncodedLen()
+	}
+	return l
+}
+
+// checkMarshal returns an error when the Message cannot be marshaled,
+// without marshaling it.
+func (m *Message) checkMarshal() error {
+	if err := m.check(); err != nil {
+		return err
+	}
+	for _, r := range m.Records {
+		if err := r.checkMarshal(); err != nil {
+			return err
 		}
 	}
-	return buffer.Bytes(), nil
+	return nil
 }
 
 func (m *Message) check() error {
 	last := len(m.Records) - 1
 
 	if last < 0 {
-		return errors.New(eNORECORDS)
+		return newParseError(ErrNoRecords, "")
 	}
 
 	if !m.Records[0].MB() {
-		return errors.New(eNOMB)
+		return recordError(ErrNoMessageBegin, 0, 0)
 	}
 
-	if !m.Records[last].ME() {
-		return errors.New(eNOME)
+	if r := m.Records[last]; !r.ME() {
+		return recordError(ErrNoMessageEnd, last, len(r.chunks)-1)
 	}
 
 	for i, r := range m.Records {
 		if i > 0 && r.MB() {
-			return errors.New(eBADMB)
+			return recordError(ErrBadMessageBegin, i, 0)
 		}
 		if i < last && r.ME() {
-			return errors.New(eBADME)
+			return recordError(ErrBadMessageEnd, i, len(r.chunks)-1)
 		}
 	}
 
 	return nil
 }
 
-// Check errors
-const (
-	eNORECORDS = "NDEF Message Check: No records"
-	eNOMB      = "NDEF Message Check: first record has not the MessageBegin flag set"
-	eNOME      = "NDEF Message Check: last record has not the MessageEnd flag set"
-	eBADMB     = "NDEF Message Check: middle record has the MessageBegin flag set"
-	eBADME     = "NDEF Message Check: middle record has the MessageEnd flag set"
-)
+// recordError returns a *ParseError for the flags of the given record
+// and chunk.
+func recordError(err error, record, chunk int) *ParseError {
+	perr := newParseError(err, FieldFlags)
+	perr.Record = record
+	perr.Chunk = chunk
+	return perr
+}
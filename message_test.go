@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/hsanjuan/go-ndef/types/generic"
 	"github.com/hsanjuan/go-ndef/types/wkt/uri"
 )
 
@@ -34,7 +35,7 @@ func ExampleMessage() {
 	// urn:nfc:wkt:U:https://github.com/hsanjuan/go-ndef
 }
 
-func ExampleSmartPoster() {
+func Example_smartPoster() {
 	// Here we create a SmartPoster Message with a title and a URL.
 	// A SmartPoster wraps an NDEF Message with several records.
 	title := NewTextRecord("The title", "en")
@@ -109,3 +110,71 @@ func TestMarhsal(t *testing.T) {
 		t.Error("Unexpected marshal result")
 	}
 }
+
+func TestMessageAppendMarshal(t *testing.T) {
+	m := NewMessageFromRecords(
+		NewURIRecord("https://example.com"),
+		NewMediaRecord("text/plain; charset=utf-8", []byte("hi")),
+		NewAbsoluteURIRecord("https://example.com/type", []byte("hi")),
+	)
+	expected, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prefix := []byte{0xAA}
+	buf, err := m.AppendMarshal(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, append([]byte{0xAA}, expected...)) {
+		t.Error("bad append")
+	}
+
+	buf = make([]byte, 0, 128)
+	allocs := testing.AllocsPerRun(10, func() {
+		buf, _ = m.AppendMarshal(buf[:0])
+	})
+	if allocs != 0 {
+		t.Error("AppendMarshal should not allocate, got", allocs)
+	}
+
+	m.Records[0].SetMB(false)
+	buf, err = m.AppendMarshal(prefix)
+	if err == nil || !bytes.Equal(buf, prefix) {
+		t.Error("expected an error and the untouched buffer")
+	}
+}
+
+func TestMessageEncodedLen(t *testing.T) {
+	idRecord := NewTextRecord("hi", "en")
+	if err := idRecord.SetID("id"); err != nil {
+		t.Fatal(err)
+	}
+	chunked, err := NewChunkedRecord(MediaType, "a/b", "",
+		generic.New(bytes.Repeat([]byte{1}, 300)), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMessageFromRecords(
+		idRecord,
+		NewSmartPosterRecord(NewURIMessage("https://example.com")),
+		NewMediaRecord("a/b", bytes.Repeat([]byte{1}, 300)),
+		chunked,
+		NewRecord(Empty, "", "", nil),
+	)
+	buf, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := m.EncodedLen(); l != len(buf) {
+		t.Errorf("expected %d bytes but got %d", len(buf), l)
+	}
+
+	allocs := testing.AllocsPerRun(10, func() {
+		m.EncodedLen()
+	})
+	if allocs != 0 {
+		t.Error("EncodedLen should not allocate, got", allocs)
+	}
+}
@@ -23,9 +23,15 @@
 // as well as to turn an Message into a bytes again.
 package ndef
 
+import "fmt"
+
+// TNF is the Type Name Format of a record, a 3-bit value which tells how
+// to interpret its type.
+type TNF byte
+
 // Possible values for the TNF Field as defined in the specification.
 const (
-	Empty = byte(iota)
+	Empty = TNF(iota)
 	NFCForumWellKnownType
 	MediaType
 	AbsoluteURI
@@ -34,3 +40,22 @@ const (
 	Unchanged
 	Reserved
 )
+
+var tnfNames = []string{
+	"Empty",
+	"NFC Forum Well-Known Type",
+	"Media Type",
+	"Absolute URI",
+	"NFC Forum External Type",
+	"Unknown",
+	"Unchanged",
+	"Reserved",
+}
+
+// String returns the name of the TNF, as in the specification.
+func (tnf TNF) String() string {
+	if int(tnf) < len(tnfNames) {
+		return tnfNames[tnf]
+	}
+	return fmt.Sprintf("TNF(%d)", byte(tnf))
+}
@@ -17,6 +17,17 @@
 
 package ndef
 
+import "testing"
+
+func TestTNFString(t *testing.T) {
+	if s := NFCForumExternalType.String(); s != "NFC Forum External Type" {
+		t.Error("unexpected name:", s)
+	}
+	if s := TNF(9).String(); s != "TNF(9)" {
+		t.Error("unexpected name:", s)
+	}
+}
+
 // func TestURIProtocols(t *testing.T) {
 // 	for i := 0; i <= 36; i++ {
 // 		r := URIProtocols[byte(i)]
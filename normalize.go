@@ -0,0 +1,98 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"strings"
+
+	"github.com/hsanjuan/go-ndef/types/absoluteuri"
+	"github.com/hsanjuan/go-ndef/types/media"
+)
+
+// Normalize puts the Message in canonical form, so that Messages with
+// the same content (see Message.Equal) are always marshaled to the same
+// bytes. In canonical form:
+//
+//   - Every record is normalized (see Record.Normalize).
+//   - Only the first record has the MB flag set, and only the last
+//     record has the ME flag set.
+//
+// Hashes of the Marshaling of normalized Messages are stable, as long as
+// the canonical form is. Any change to it will be considered a breaking
+// change.
+func (m *Message) Normalize() {
+	last := len(m.Records) - 1
+	for i, r := range m.Records {
+		r.Normalize()
+		r.SetMB(i == 0)
+		r.SetME(i == last)
+	}
+}
+
+// Normalize puts the Record in canonical form, keeping its TNF, type, ID,
+// payload and its MB and ME flags. In canonical form:
+//
+//   - The record has a single chunk, unless the payload is longer than
+//     2^32-1 bytes, in which case it is split in as few chunks as possible.
+//   - TYPE_LENGTH, ID_LENGTH and PAYLOAD_LENGTH match the actual fields.
+//   - The SR flag is set when the payload is shorter than 256 bytes.
+//   - The IL flag is set when the record has an ID.
+//   - Types which are compared loosely by Record.Equal are in canonical
+//     form: Media types as in media.NormalizeType, Absolute URIs as in
+//     absoluteuri.NormalizeType and NFC Forum External types in
+//     lowercase. The type is kept when its canonical form is longer
+//     than 255 bytes.
+//   - The payload of a Smart Poster is the Marshaling of its message in
+//     canonical form, as long as it can be decoded and marshaled.
+func (r *Record) Normalize() {
+	if r.Empty() {
+		return
+	}
+
+	payload := r.payloadBytes()
+	if msg, ok := r.smartPosterMessage(); ok {
+		msg.Normalize()
+		if msgBytes, err := msg.Marshal(); err == nil {
+			payload = msgBytes
+		}
+	}
+
+	// cannot fail with a valid chunk size.
+	chunks, _ := newChunks(r.TNF(), canonicalType(r.TNF(), r.Type()), r.ID(), payload,
+		maxChunkLength)
+	chunks[0].MB = r.MB()
+	chunks[len(chunks)-1].ME = r.ME()
+	r.chunks = chunks
+}
+
+// canonicalType returns the canonical form of a type with the given TNF.
+func canonicalType(tnf TNF, typ string) string {
+	canonical := typ
+	switch tnf {
+	case MediaType:
+		canonical = media.NormalizeType(typ)
+	case AbsoluteURI:
+		canonical = absoluteuri.NormalizeType(typ)
+	case NFCForumExternalType:
+		canonical = strings.ToLower(typ)
+	}
+	if len(canonical) > 255 {
+		return typ
+	}
+	return canonical
+}
@@ -0,0 +1,139 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	payload := bytes.Repeat([]byte("abc"), 100)
+	m := NewMessageFromRecords(
+		NewMediaRecord("text/plain", payload),
+		NewSmartPosterRecord(NewURIMessage("https://example.com")),
+		NewTextRecord("hi", "en"),
+	)
+	expected, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunk everything, including the smart poster contents.
+	opts := MarshalOptions{MaxChunkSize: 4}
+	spBytes, err := opts.Marshal(NewURIMessage("https://example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Records[1].SetPayloadBytes(spBytes); err != nil {
+		t.Fatal(err)
+	}
+	chunked, err := opts.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m2 := &Message{}
+	if _, err := m2.Unmarshal(chunked); err != nil {
+		t.Fatal(err)
+	}
+
+	// Make the flags and lengths drift
+	text := m2.Records[2].chunks[0]
+	text.SR = false
+	text.IL = true
+	m2.Records[0].SetMB(false)
+	m2.Records[2].SetMB(true)
+	m2.Records[2].SetME(false)
+
+	m2.Normalize()
+	got, err := m2.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("unexpected normalized message:\n%x\n%x", got, expected)
+	}
+	for _, r := range m2.Records {
+		if len(r.chunks) != 1 {
+			t.Error("records should have a single chunk")
+		}
+	}
+}
+
+func TestNormalizeRecord(t *testing.T) {
+	r := NewURIRecord("https://example.com")
+	r.SetMB(false)
+	r.chunks[0].PayloadLength = 300
+	r.chunks[0].SR = false
+	r.Normalize()
+
+	c := r.chunks[0]
+	if c.MB || !c.ME {
+		t.Error("MB and ME should be kept")
+	}
+	if !c.SR || c.PayloadLength != uint64(len(c.Payload)) {
+		t.Error("the payload length should be fixed")
+	}
+
+	empty := &Record{}
+	empty.Normalize()
+	if !empty.Empty() {
+		t.Error("empty records should stay empty")
+	}
+}
+
+func TestNormalizeEqual(t *testing.T) {
+	p := []byte{1, 2, 3}
+	pairs := [][2]*Record{
+		{NewMediaRecord("Image/PNG", p), NewMediaRecord("image/png", p)},
+		{
+			NewMediaRecord("text/plain;Charset=UTF-8 ;format=flowed", p),
+			NewMediaRecord("TEXT/plain; format=flowed; charset=\"UTF-8\"", p),
+		},
+		{
+			NewMediaRecord("text/plain; charset=UTF-8", p),
+			NewMediaRecord("text/plain; charset=utf-8", p),
+		},
+		{NewMediaRecord("Not A Type", p), NewMediaRecord("not a type", p)},
+		{
+			NewAbsoluteURIRecord("HTTP://Example.com/%7euser", p),
+			NewAbsoluteURIRecord("http://example.com/~user", p),
+		},
+		{NewExternalRecord("Example.com:Foo", p), NewExternalRecord("example.com:foo", p)},
+	}
+	for _, pair := range pairs {
+		a, b := NewMessageFromRecords(pair[0]), NewMessageFromRecords(pair[1])
+		if !a.Equal(b) {
+			t.Fatalf("%q and %q should be equal", pair[0].Type(), pair[1].Type())
+		}
+		a.Normalize()
+		b.Normalize()
+		aBytes, err := a.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		bBytes, err := b.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(aBytes, bBytes) {
+			t.Errorf("%q and %q should be normalized to the same bytes",
+				pair[0].Type(), pair[1].Type())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef_test
+
+import (
+	"testing"
+
+	"github.com/hsanjuan/go-ndef"
+	"github.com/hsanjuan/go-ndef/types/generic"
+)
+
+// handoverPayload is a simplified Handover Select payload: a version
+// byte followed by an NDEF Message with local "ac" records.
+type handoverPayload struct {
+	Version byte
+	Message *ndef.Message
+}
+
+func (h *handoverPayload) String() string { return h.Message.String() }
+func (h *handoverPayload) Type() string   { return "urn:nfc:wkt:Hs" }
+func (h *handoverPayload) Len() int       { return len(h.Marshal()) }
+
+func (h *handoverPayload) Marshal() []byte {
+	buf, _ := h.Message.Marshal()
+	return append([]byte{h.Version}, buf...)
+}
+
+func (h *handoverPayload) Unmarshal(buf []byte) {
+	h.Decode(buf)
+}
+
+func (h *handoverPayload) Decode(buf []byte) error {
+	opts := ndef.DecodeOptions{}.WithParent(ndef.NFCForumWellKnownType, "Hs")
+	return h.DecodeWithOptions(buf, opts)
+}
+
+func (h *handoverPayload) DecodeWithOptions(buf []byte, opts ndef.DecodeOptions) error {
+	h.Message = &ndef.Message{}
+	if len(buf) == 0 {
+		return nil
+	}
+	h.Version = buf[0]
+	_, _, err := opts.Unmarshal(buf[1:], h.Message)
+	return err
+}
+
+type alternativeCarrier struct {
+	generic.Payload
+}
+
+func TestOptionsDecoderLocalTypes(t *testing.T) {
+	reg := ndef.DefaultRegistry.Clone()
+	reg.Register(ndef.NFCForumWellKnownType, "Hs", func(ndef.TNF, string) ndef.RecordPayload {
+		return new(handoverPayload)
+	})
+	reg.RegisterLocal("Hs", "ac", func(ndef.TNF, string) ndef.RecordPayload {
+		return new(alternativeCarrier)
+	})
+
+	ac := ndef.NewRecord(ndef.NFCForumWellKnownType, "ac", "",
+		generic.New([]byte{0x01, 0x01, 0x30, 0x00}))
+	hs := &handoverPayload{
+		Version: 0x12,
+		Message: ndef.NewMessageFromRecords(ac),
+	}
+	buf, err := ndef.NewMessageFromRecords(
+		ndef.NewRecord(ndef.NFCForumWellKnownType, "Hs", "", hs),
+	).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &ndef.Message{}
+	opts := ndef.DecodeOptions{Registry: reg}
+	if _, _, err := opts.Unmarshal(buf, msg); err != nil {
+		t.Fatal(err)
+	}
+	pl, err := msg.Records[0].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, ok := pl.(*handoverPayload)
+	if !ok || decoded.Version != 0x12 {
+		t.Fatalf("expected the registered payload, got %T", pl)
+	}
+	nested, err := decoded.Message.Records[0].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nested.(*alternativeCarrier); !ok {
+		t.Errorf("expected the local payload, got %T", nested)
+	}
+}
@@ -21,9 +21,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 
 	"github.com/hsanjuan/go-ndef/types/absoluteuri"
 	"github.com/hsanjuan/go-ndef/types/ext"
+	"github.com/hsanjuan/go-ndef/types/generic"
 	"github.com/hsanjuan/go-ndef/types/media"
 	"github.com/hsanjuan/go-ndef/types/wkt/text"
 	"github.com/hsanjuan/go-ndef/types/wkt/uri"
@@ -33,29 +35,82 @@ import (
 // part of a single NDEF Message.
 type Record struct {
 	chunks []*recordChunk
+
+	// options used to decode the record, which apply to its payload too.
+	opts DecodeOptions
 }
 
 // NewRecord returns a single-chunked record with the given options.
 // Use a generic.Payload if you want to use a custom byte-slice for payload.
-func NewRecord(tnf byte, typ string, id string, payload RecordPayload) *Record {
+//
+// Payloads which do not fit in a single chunk (longer than 2^32-1 bytes)
+// are split in several chunks.
+func NewRecord(tnf TNF, typ string, id string, payload RecordPayload) *Record {
 	var payloadBytes []byte
 	if payload != nil {
 		payloadBytes = payload.Marshal()
 	}
 
-	chunk := newChunk(
+	// cannot fail with a valid chunk size.
+	chunks, _ := newChunks(
 		tnf,
 		typ,
 		id,
 		payloadBytes,
+		maxChunkLength,
 	)
 	return &Record{
-		chunks: []*recordChunk{chunk},
+		chunks: chunks,
+	}
+}
+
+// NewChunkedRecord returns a record with the given options whose payload
+// is split in chunks carrying up to maxChunkSize bytes each. If the
+// payload fits in a single chunk, the record is not chunked.
+//
+// An error is returned if the record cannot be represented with the given
+// options.
+func NewChunkedRecord(tnf TNF, typ string, id string, payload RecordPayload, maxChunkSize int) (*Record, error) {
+	var payloadBytes []byte
+	if payload != nil {
+		payloadBytes = payload.Marshal()
+	}
+
+	chunks, err := newChunks(tnf, typ, id, payloadBytes, maxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	r := &Record{
+		chunks: chunks,
+	}
+	if err := r.check(); err != nil {
+		return nil, err
+	}
+	for _, chunk := range r.chunks {
+		if err := chunk.Check(); err != nil {
+			return nil, err
+		}
 	}
+	return r, nil
+}
+
+// NewCheckedRecord is like NewRecord, but returns an error when the record
+// cannot be marshaled or when its type does not follow the syntax required
+// by its TNF (see CheckType). It is meant to catch malformed types when
+// building records, while decoding accepts them.
+func NewCheckedRecord(tnf TNF, typ string, id string, payload RecordPayload) (*Record, error) {
+	r := NewRecord(tnf, typ, id, payload)
+	if err := r.checkType(); err != nil {
+		return nil, err
+	}
+	if err := r.checkMarshal(); err != nil {
+		return nil, err
+	}
+	return r, nil
 }
 
 // TNF returns the Type Name Format (3 bits) associated to this Record.
-func (r *Record) TNF() byte {
+func (r *Record) TNF() TNF {
 	if r.Empty() {
 		return 0
 	}
@@ -80,19 +135,69 @@ func (r *Record) ID() string {
 
 // Payload returns the RecordPayload for this record. It will use
 // one of the supported types, or otherwise a generic.Payload.
+//
+// The RecordPayload is obtained from the Registry in the DecodeOptions
+// used to parse the record, or from the DefaultRegistry.
+//
+// When the payload is malformed, the error is returned along with
+// the RecordPayload, which holds whatever could be parsed.
+//
+// The RecordPayload does not share memory with the Record, unless the
+// Record was decoded with DecodeOptions.ZeroCopy, in which case it may
+// alias the decoded input.
 func (r *Record) Payload() (RecordPayload, error) {
 	if r.Empty() {
 		return nil, errors.New("empty record")
 	}
 
+	return makeRecordPayload(r.TNF(), r.Type(), r.payloadBytes(), r.opts)
+}
+
+// PayloadBytes returns a copy of the raw payload of the record, which
+// is the concatenation of the payloads of its chunks. See SetPayloadBytes.
+func (r *Record) PayloadBytes() []byte {
+	return append([]byte(nil), r.payloadBytes()...)
+}
+
+// payloadBytes returns the payload of the record, which is the
+// concatenation of the payloads of its chunks. It is only shared with
+// the chunk when the record was decoded with DecodeOptions.ZeroCopy.
+func (r *Record) payloadBytes() []byte {
+	if len(r.chunks) == 1 && r.opts.ZeroCopy {
+		return r.chunks[0].Payload
+	}
 	var buf bytes.Buffer
 	for _, chunk := range r.chunks {
-		_, err := buf.Write(chunk.Payload)
-		if err != nil {
-			return nil, err
-		}
+		buf.Write(chunk.Payload)
 	}
-	return makeRecordPayload(r.TNF(), r.Type(), buf.Bytes()), nil
+	return buf.Bytes()
+}
+
+// payloadLen returns the length of the payload of the record, without
+// putting it together.
+func (r *Record) payloadLen() int {
+	l := 0
+	for _, chunk := range r.chunks {
+		l += len(chunk.Payload)
+	}
+	return l
+}
+
+// rechunk returns the chunks for this record, so that their payloads
+// are at most maxChunkSize bytes long. When maxChunkSize is 0, the
+// current chunks are returned.
+func (r *Record) rechunk(maxChunkSize int) ([]*recordChunk, error) {
+	if maxChunkSize == 0 || r.Empty() {
+		return r.chunks, nil
+	}
+	chunks, err := newChunks(r.TNF(), r.Type(), r.ID(), r.payloadBytes(),
+		maxChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	chunks[0].MB = r.MB()
+	chunks[len(chunks)-1].ME = r.ME()
+	return chunks, nil
 }
 
 // Empty returns true if this record has no chunks.
@@ -135,6 +240,97 @@ func (r *Record) SetME(b bool) {
 	r.chunks[len(r.chunks)-1].ME = b
 }
 
+// SetTNF sets the Type Name Format of the record. The current type,
+// ID and payload must be allowed with it, i.e. an Empty record cannot
+// have any of them.
+func (r *Record) SetTNF(tnf TNF) error {
+	if tnf > Reserved || tnf == Unchanged {
+		return ErrInvalidTNF
+	}
+	return r.setHeader(func(chunk *recordChunk) {
+		chunk.TNF = tnf
+	})
+}
+
+// SetType sets the type of the record, which must be allowed by its TNF.
+func (r *Record) SetType(typ string) error {
+	return r.setHeader(func(chunk *recordChunk) {
+		chunk.Type = typ
+	})
+}
+
+// SetID sets the ID of the record. An empty ID removes it.
+func (r *Record) SetID(id string) error {
+	return r.setHeader(func(chunk *recordChunk) {
+		chunk.ID = id
+	})
+}
+
+// SetPayload sets the payload of the record to the Marshaling of the
+// given RecordPayload. A nil payload empties it. The type of the record
+// is not modified. See SetPayloadBytes.
+func (r *Record) SetPayload(payload RecordPayload) error {
+	var payloadBytes []byte
+	if payload != nil {
+		payloadBytes = payload.Marshal()
+	}
+	return r.setPayload(payloadBytes)
+}
+
+// SetPayloadBytes sets the payload of the record to a copy of the given
+// bytes. Chunked records are split again in chunks of the same size,
+// while the rest get more chunks only when the payload does not fit
+// in one.
+func (r *Record) SetPayloadBytes(payload []byte) error {
+	return r.setPayload(append([]byte(nil), payload...))
+}
+
+func (r *Record) setPayload(payload []byte) error {
+	maxChunkSize := maxChunkLength
+	if len(r.chunks) > 1 && r.chunks[0].PayloadLength > 0 {
+		maxChunkSize = int(r.chunks[0].PayloadLength)
+	}
+	chunks, err := newChunks(r.TNF(), r.Type(), r.ID(), payload,
+		maxChunkSize)
+	if err != nil {
+		return err
+	}
+	if !r.Empty() {
+		chunks[0].MB = r.MB()
+		chunks[len(chunks)-1].ME = r.ME()
+	}
+	for _, chunk := range chunks {
+		if err := chunk.Check(); err != nil {
+			return err
+		}
+	}
+	r.chunks = chunks
+	return nil
+}
+
+// setHeader modifies a copy of the first chunk with fn, fixes the length
+// fields and replaces the first chunk with it, as long as it is valid.
+func (r *Record) setHeader(fn func(chunk *recordChunk)) error {
+	chunk := newChunk(Empty, "", "", nil)
+	if !r.Empty() {
+		c := *r.chunks[0]
+		chunk = &c
+	}
+	fn(chunk)
+	chunk.TypeLength = byte(len(chunk.Type))
+	chunk.IDLength = byte(len(chunk.ID))
+	chunk.IL = len(chunk.ID) > 0
+	if err := chunk.Check(); err != nil {
+		return err
+	}
+	if r.Empty() {
+		r.chunks = []*recordChunk{chunk}
+		return nil
+	}
+	r.chunks[0] = chunk
+	return nil
+}
+
 // NewTextRecord returns a new Record with a
 // Payload of Text [Well-Known] Type.
 func NewTextRecord(textVal, language string) *Record {
@@ -159,54 +355,126 @@ func NewSmartPosterRecord(msg *Message) *Record {
 // NewMediaRecord returns a new Record with a
 // Media type (per RFC-2046) as payload.
 //
-// mimeType is something like "text/json" or "image/jpeg".
+// mimeType is something like "text/json" or "image/jpeg". It is used as
+// given: see NewCheckedMediaRecord.
 func NewMediaRecord(mimeType string, payload []byte) *Record {
 	pl := media.New(mimeType, payload)
 	return NewRecord(MediaType, mimeType, "", pl)
 }
 
+// NewCheckedMediaRecord is like NewMediaRecord, but returns the error
+// from media.CheckType when mimeType is not a valid media type, as in
+// "image" or "text/plain; charset".
+func NewCheckedMediaRecord(mimeType string, payload []byte) (*Record, error) {
+	pl, err := media.NewChecked(mimeType, payload)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecord(MediaType, mimeType, "", pl), nil
+}
+
 // NewAbsoluteURIRecord returns a new Record with a
 // Payload of Absolute URI type.
 //
 // AbsoluteURI means that the type of the payload for this record is
 // defined by an URI resource. It is not supposed to be used to
-// describe an URI. For that, use NewURIRecord().
+// describe an URI. For that, use NewURIRecord(). Use
+// NewCheckedAbsoluteURIRecord to make sure that typeURI is an absolute
+// URI.
 func NewAbsoluteURIRecord(typeURI string, payload []byte) *Record {
 	pl := absoluteuri.New(typeURI, payload)
 	return NewRecord(AbsoluteURI, typeURI, "", pl)
 }
 
+// NewCheckedAbsoluteURIRecord is like NewAbsoluteURIRecord, but returns
+// the error from absoluteuri.CheckType when typeURI is not an absolute
+// URI.
+func NewCheckedAbsoluteURIRecord(typeURI string, payload []byte) (*Record, error) {
+	pl, err := absoluteuri.NewChecked(typeURI, payload)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecord(AbsoluteURI, typeURI, "", pl), nil
+}
+
 // NewExternalRecord returns a new Record with a
 // Payload of NFC Forum external type.
+//
+// The type name is used as given. See NewCheckedExternalRecord to reject
+// names which do not have the "<domain>:<name>" form.
 func NewExternalRecord(extType string, payload []byte) *Record {
 	pl := ext.New(extType, payload)
 	return NewRecord(NFCForumExternalType, extType, "", pl)
 }
 
+// NewCheckedExternalRecord is like NewExternalRecord, but returns the
+// error from ext.CheckType when extType is not a valid external type
+// name.
+func NewCheckedExternalRecord(extType string, payload []byte) (*Record, error) {
+	pl, err := ext.NewChecked(extType, payload)
+	if err != nil {
+		return nil, err
+	}
+	return NewRecord(NFCForumExternalType, extType, "", pl), nil
+}
+
+// CheckType verifies that typ follows the syntax required for record
+// types with the given TNF: external type names (see ext.CheckType),
+// media types (see media.CheckType) and absolute URIs (see
+// absoluteuri.CheckType). It returns ErrBadExternalType,
+// ErrBadMediaType or ErrBadAbsoluteURIType respectively, and nil for
+// other TNFs, whose types are not checked.
+func CheckType(tnf TNF, typ string) error {
+	switch tnf {
+	case NFCForumExternalType:
+		if ext.CheckType(typ) != nil {
+			return ErrBadExternalType
+		}
+	case MediaType:
+		if media.CheckType(typ) != nil {
+			return ErrBadMediaType
+		}
+	case AbsoluteURI:
+		if absoluteuri.CheckType(typ) != nil {
+			return ErrBadAbsoluteURIType
+		}
+	}
+	return nil
+}
+
 // String a string representation of the payload of the record, prefixed
-// by the URN of the resource.
+// by the type given by the payload (see RecordPayload). For Media and
+// Absolute URI types, it is the bare type, and not Record.TypeURN().
 //
 // Note that not all NDEF Payloads are supported, and that custom types/payloads
 // are considered not printable. In those cases, a generic RecordPayload is
 // used and an explanatory message is returned instead.
 // See submodules under "types/" for a list of supported types.
+//
+// Malformed payloads are printed as far as they could be decoded,
+// followed by the decoding error in parenthesis.
 func (r *Record) String() string {
 	pl, err := r.Payload()
-	if err != nil {
+	if pl == nil {
 		return err.Error()
 	}
-	return pl.Type() + ":" + pl.String()
+	str := pl.Type() + ":" + pl.String()
+	if err != nil {
+		str += " (" + err.Error() + ")"
+	}
+	return str
 }
 
-// Inspect provides a string with information about this record.
-// For a String representation of the contents use String().
+// Inspect provides a string with information about this record, including
+// any error decoding its payload. For a String representation of the
+// contents use String().
 func (r *Record) Inspect() string {
 	if r.Empty() {
 		return "Empty record"
 	}
 
 	pl, err := r.Payload()
-	if err != nil {
+	if pl == nil {
 		return err.Error()
 	}
 
@@ -216,7 +484,18 @@ func (r *Record) Inspect() string {
 	str += fmt.Sprintf("ID: %s\n", r.ID())
 	str += fmt.Sprintf("MB: %t\n", r.MB())
 	str += fmt.Sprintf("ME: %t\n", r.ME())
-	str += fmt.Sprintf("Payload Length: %d", pl.Len())
+	str += fmt.Sprintf("Payload Length: %d", r.payloadLen())
+	if mediaPl, ok := pl.(*media.Payload); ok && mediaPl.Len() > 0 {
+		str += fmt.Sprintf("\nPayload: %s", mediaPl)
+	}
+	if _, ok := pl.(*generic.Payload); ok {
+		if typ, c := r.Sniff(); c > ConfidenceNone {
+			str += fmt.Sprintf("\nContent: %s (%s confidence)", typ, c)
+		}
+	}
+	if err != nil {
+		str += fmt.Sprintf("\nPayload Error: %s", err)
+	}
 	return str
 }
 
@@ -230,14 +509,26 @@ func (r *Record) Inspect() string {
 // Returns how many bytes were parsed from the slice (record length) or
 // an error if something went wrong.
 func (r *Record) Unmarshal(buf []byte) (rLen int, err error) {
-	rLen = 0
+	rLen, _, err = DecodeOptions{}.UnmarshalRecord(buf, r)
+	return rLen, err
+}
+
+// readChunks reads chunks from src until a chunk with the CF flag
+// cleared is read or there is no more data.
+func (r *Record) readChunks(src chunkSource, st *decodeState) error {
 	var chunks []*recordChunk
-	for rLen < len(buf) {
-		chunk := &recordChunk{}
-		chunkSize, err := chunk.Unmarshal(buf[rLen:])
-		rLen += chunkSize
+	st.recordPayload = 0
+	for {
+		st.chunk = len(chunks)
+		chunk, err := src.next(st)
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = st.addPayload(chunk)
+		}
 		if err != nil {
-			return rLen, err
+			return setIndex(err, -1, len(chunks))
 		}
 		chunks = append(chunks, chunk)
 
@@ -248,95 +539,127 @@ func (r *Record) Unmarshal(buf []byte) (rLen int, err error) {
 		}
 	}
 
+	st.chunk = -1
 	r.chunks = chunks
-	err = r.check()
-	return rLen, err
+	r.opts = st.opts
+	return r.locate(r.check())
 }
 
-// Marshal returns the byte representation of a Record. It does this
-// by producing a single record chunk.
-//
-// Note that if the original Record was unmarshaled from many chunks,
-// the recovery is not possible anymore.
+// locate sets the offset of a *ParseError affecting a chunk of this
+// record.
+func (r *Record) locate(err error) error {
+	var perr *ParseError
+	if errors.As(err, &perr) && perr.Chunk >= 0 && perr.Chunk < len(r.chunks) {
+		chunk := r.chunks[perr.Chunk]
+		return chunk.locate(err, chunk.offset)
+	}
+	return err
+}
+
+// Marshal returns the byte representation of a Record, made of the
+// chunks it has. Use MarshalOptions to chunk it differently.
 func (r *Record) Marshal() ([]byte, error) {
+	return r.AppendMarshal(nil)
+}
+
+// AppendMarshal appends the byte representation of a Record to dst and
+// returns the extended buffer, as Marshal does. It does not allocate when
+// dst has enough capacity. On error, dst is returned unmodified.
+func (r *Record) AppendMarshal(dst []byte) ([]byte, error) {
 	err := r.check()
 	if err != nil {
-		return nil, err
+		return dst, err
 	}
 
-	var buf bytes.Buffer
+	buf := dst
 	for _, chunk := range r.chunks {
-		chunkBytes, err := chunk.Marshal()
+		buf, err = chunk.appendMarshal(buf)
 		if err != nil {
-			return buf.Bytes(), err
+			return dst, err
 		}
-		_, err = buf.Write(chunkBytes)
-		if err != nil {
-			return buf.Bytes(), err
+	}
+	return buf, nil
+}
+
+// EncodedLen returns the length of the Marshaling of a valid Record,
+// computed from the sizes of its chunks without marshaling it.
+func (r *Record) EncodedLen() int {
+	l := 0
+	for _, chunk := range r.chunks {
+		l += chunk.encodedLen()
+	}
+	return l
+}
+
+// checkType checks the type of the record. See CheckType.
+func (r *Record) checkType() error {
+	if len(r.chunks) == 0 {
+		return nil
+	}
+	if err := CheckType(r.TNF(), r.Type()); err != nil {
+		return chunkError(err, FieldType, 0)
+	}
+	return nil
+}
+
+// checkMarshal returns an error when the Record cannot be marshaled,
+// without marshaling it.
+func (r *Record) checkMarshal() error {
+	if err := r.check(); err != nil {
+		return err
+	}
+	for _, chunk := range r.chunks {
+		if err := chunk.Check(); err != nil {
+			return err
 		}
 	}
-	return buf.Bytes(), nil
+	return nil
 }
 
 func (r *Record) check() error {
 	chunksLen := len(r.chunks)
 	last := chunksLen - 1
 	if chunksLen == 0 {
-		return errors.New(eNOCHUNKS)
+		return newParseError(ErrNoChunks, "")
 	}
 	if chunksLen == 1 && r.chunks[0].CF {
-		return errors.New(eFIRSTCHUNKED)
+		return chunkError(ErrSingleChunked, FieldFlags, 0)
 	}
 	if r.chunks[0].CF && r.chunks[last].CF {
-		return errors.New(eLASTCHUNKED)
+		return chunkError(ErrLastChunked, FieldFlags, last)
 	}
 
 	if chunksLen > 1 {
-		chunksWithoutCF := 0
-		chunksWithIL := 0
-		chunksWithTypeLength := 0
-		chunksWithoutUnchangedType := 0
-		for i, r := range r.chunks {
-			// Check CF in all but the last
-			if !r.CF && i != last {
-				chunksWithoutCF++
-			}
-			// Check IL in all but first
-			if r.IL && i != 0 {
-				chunksWithIL++
-			}
-			// TypeLength should be zero except in the first
-			if r.TypeLength > 0 && i != 0 {
-				chunksWithTypeLength++
-			}
-			// All but first chunk should have TNF to 0x06
-			if r.TNF != Unchanged && i != 0 {
-				chunksWithoutUnchangedType++
+		// Check CF in all but the last
+		for i, c := range r.chunks[:last] {
+			if !c.CF {
+				return chunkError(ErrMissingChunkFlag, FieldFlags, i)
 			}
 		}
-		if chunksWithoutCF > 0 {
-			return errors.New(eCFMISSING)
-		}
-		if chunksWithIL > 0 {
-			return errors.New(eBADIL)
+		// Check IL in all but first
+		for i, c := range r.chunks {
+			if c.IL && i != 0 {
+				return chunkError(ErrBadIL, FieldFlags, i)
+			}
 		}
-		if chunksWithTypeLength > 0 {
-			return errors.New(eBADTYPELENGTH)
+		// TypeLength should be zero except in the first
+		for i, c := range r.chunks {
+			if c.TypeLength > 0 && i != 0 {
+				return chunkError(ErrBadTypeLength, FieldTypeLength, i)
+			}
 		}
-		if chunksWithoutUnchangedType > 0 {
-			return errors.New(eBADTNF)
+		// All but first chunk should have TNF to 0x06
+		for i, c := range r.chunks {
+			if c.TNF != Unchanged && i != 0 {
+				return chunkError(ErrBadTNF, FieldFlags, i)
+			}
 		}
 	}
 	return nil
 }
 
-// Set some short-hands for the errors that can happen on check().
-const (
-	eNOCHUNKS      = "NDEF Record Check: No chunks"
-	eFIRSTCHUNKED  = "NDEF Record Check: A single record cannot have the Chunk flag set"
-	eLASTCHUNKED   = "NDEF Record Check: Last record cannot have the Chunk flag set"
-	eCFMISSING     = "NDEF Record Check: Chunk Flag missing from some records"
-	eBADIL         = "NDEF Record Check: IL flag is set on a middle or final chunk"
-	eBADTYPELENGTH = "NDEF Record Check: A middle or last chunk has TypeLength != 0"
-	eBADTNF        = "NDEF Record Check: A middle or last chunk TNF is not UNCHANGED"
-)
+func chunkError(err error, field string, chunk int) *ParseError {
+	perr := newParseError(err, field)
+	perr.Chunk = chunk
+	return perr
+}
@@ -18,10 +18,9 @@
 package ndef
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
-	"runtime"
+	"io"
 )
 
 // RecordChunk represents how a Record is actually stored
@@ -34,13 +33,17 @@ type recordChunk struct {
 	CF            bool   // Chunk Flag
 	SR            bool   // Short record
 	IL            bool   // ID length field present
-	TNF           byte   // Type name format (3 bits)
+	TNF           TNF    // Type name format (3 bits)
 	TypeLength    byte   // Type Length
 	IDLength      byte   // Length of the ID field
 	PayloadLength uint64 // Length of the Payload.
 	Type          string // Type of the payload. Must follow TNF
 	ID            string // An URI (per RFC 3986)
 	Payload       []byte // Payload
+
+	// position of the chunk in the input it was parsed from, used
+	// to locate errors.
+	offset int64
 }
 
 // Reset clears up all the fields of the Record and sets them to their
@@ -60,10 +63,22 @@ func (r *recordChunk) Reset() {
 	r.Payload = []byte{}
 }
 
+// maxPayloadLength is the largest payload a single chunk can carry, as
+// the PAYLOAD_LENGTH field is 4 bytes long (2^32-1).
+const maxPayloadLength = 4294967295
+
+// maxChunkLength is the largest chunk size usable as an int: it is
+// maxPayloadLength except on 32-bit platforms, where slices cannot hold
+// that many bytes anyways. Both limits are 2^n-1, so masking one with the
+// other yields the smallest.
+const maxChunkLength = int(uint64(^uint(0)>>1) & maxPayloadLength)
+
 // New returns a single RecordChunk with the given options. This chunk can be
 // used directly to make a single-chunk NDEF Record on a single-record NDEF
 // message: the MB and ME fields are set to true.
-func newChunk(tnf byte, typ string, id string, payload []byte) *recordChunk {
+//
+// Payloads longer than maxPayloadLength need to be split with newChunks().
+func newChunk(tnf TNF, typ string, id string, payload []byte) *recordChunk {
 	chunk := &recordChunk{}
 	chunk.Reset()
 	chunk.MB = true        // Message-begin
@@ -77,18 +92,51 @@ func newChunk(tnf byte, typ string, id string, payload []byte) *recordChunk {
 	chunk.ID = id
 
 	payloadLen := uint64(len(payload))
-	if payloadLen > 4294967295 { //2^32-1. 4GB message max.
-		payloadLen = 4294967295
-	}
 	chunk.SR = payloadLen < 256 // Short record vs. Long
 	chunk.PayloadLength = payloadLen
-
-	// FIXME: If payload is greater than 2^32 - 1
-	// we'll truncate without warning.
-	chunk.Payload = payload[:payloadLen]
+	chunk.Payload = payload
 	return chunk
 }
 
+// newChunks returns the chunks for a record with the given options. The
+// payload is split so that no chunk carries more than maxChunkSize bytes.
+// A single chunk is returned when the payload fits in it.
+//
+// As in newChunk, the MB and ME flags are set in the first and last chunks.
+func newChunks(tnf TNF, typ string, id string, payload []byte, maxChunkSize int) ([]*recordChunk, error) {
+	if maxChunkSize <= 0 || uint64(maxChunkSize) > maxPayloadLength {
+		return nil, ErrBadChunkSize
+	}
+	if len(payload) <= maxChunkSize {
+		return []*recordChunk{newChunk(tnf, typ, id, payload)}, nil
+	}
+
+	var chunks []*recordChunk
+	for len(payload) > 0 {
+		n := maxChunkSize
+		if n > len(payload) {
+			n = len(payload)
+		}
+		var chunk *recordChunk
+		if len(chunks) == 0 {
+			chunk = newChunk(tnf, typ, id, payload[:n])
+		} else {
+			// Middle and terminating chunks
+			chunk = newChunk(Unchanged, "", "", payload[:n])
+		}
+		chunk.MB = false
+		chunk.ME = false
+		chunk.CF = true
+		chunks = append(chunks, chunk)
+		payload = payload[n:]
+	}
+	chunks[0].MB = true
+	last := chunks[len(chunks)-1]
+	last.ME = true
+	last.CF = false
+	return chunks, nil
+}
+
 // Provide a string with information about this record chunk.
 // Records' payload do not make sense without having compiled a whole Record
 // so they are not dealed with here.
@@ -113,61 +161,144 @@ func (r *recordChunk) String() string {
 // parsing.
 //
 // Returns how many bytes were parsed from the slice (record length) or
-// an error if something went wrong.
+// an error if something went wrong. Errors are of type *ParseError, with
+// offsets relative to the start of buf.
 func (r *recordChunk) Unmarshal(buf []byte) (rLen int, err error) {
-	// Handle errors that are produced by getByte() and getBytes()
-	defer func() {
-		if r := recover(); r != nil {
-			if _, ok := r.(runtime.Error); ok {
-				panic(r)
-			}
-			err = r.(error)
-			err = errors.New("Record.Unmarshal: " + err.Error())
-		}
-	}()
-	r.Reset()
-	bytesBuf := bytes.NewBuffer(buf)
+	return r.unmarshal(buf, newDecodeState(DecodeOptions{}))
+}
 
-	firstByte := getByte(bytesBuf)
-	r.MB = (firstByte >> 7 & 0x1) == 1
-	r.ME = (firstByte >> 6 & 0x1) == 1
-	r.CF = (firstByte >> 5 & 0x1) == 1
-	r.SR = (firstByte >> 4 & 0x1) == 1
-	r.IL = (firstByte >> 3 & 0x1) == 1
-	r.TNF = firstByte & 0x7
+// unmarshal parses a chunk following the decoding options. Offsets in
+// errors are relative to st.offset.
+func (r *recordChunk) unmarshal(buf []byte, st *decodeState) (rLen int, err error) {
+	if !st.lenient() || len(buf) == 0 || (buf[0]>>4&0x1) == 0 {
+		return r.parse(buf, st, false)
+	}
 
-	r.TypeLength = getByte(bytesBuf)
+	// Short records are sometimes written with a 4-byte PAYLOAD_LENGTH.
+	// If the chunk does not make sense as a short record, see if it
+	// does as a regular one.
+	strict := newDecodeState(DecodeOptions{})
+	strict.offset = st.offset
+	if rLen, err = r.parse(buf, strict, false); err == nil {
+		return rLen, nil
+	}
+	if rLen, err = r.parse(buf, strict, true); err == nil {
+		perr := newParseError(ErrBadShortRecord, FieldFlags)
+		perr.Offset = st.offset
+		st.warn(perr)
+		return rLen, nil
+	}
+	return r.parse(buf, st, false)
+}
 
-	if r.SR { //This is a short record
-		r.PayloadLength = uint64(getByte(bytesBuf))
-	} else { // Regular record
-		r.PayloadLength = bytesToUint64(getBytes(bytesBuf, 4))
+// parse does the actual parsing. When longRecord is true, the SR flag
+// is ignored.
+func (r *recordChunk) parse(buf []byte, st *decodeState, longRecord bool) (rLen int, err error) {
+	r.Reset()
+	if len(buf) == 0 {
+		return 0, r.truncated(FieldFlags, st.offset)
 	}
-	if r.IL {
-		r.IDLength = getByte(bytesBuf)
+
+	r.unmarshalFlags(buf[0])
+	if longRecord {
+		r.SR = false
 	}
-	r.Type = string(getBytes(bytesBuf, int(r.TypeLength)))
-	if r.IL {
-		r.ID = string(getBytes(bytesBuf, int(r.IDLength)))
+	headerLen := chunkHeaderLen(r.flags())
+	if len(buf) < headerLen {
+		// The flags tell us which fields should be there
+		switch {
+		case len(buf) == 1:
+			return 0, r.truncated(FieldTypeLength, st.offset+1)
+		case r.IL && len(buf) == headerLen-1:
+			return 0, r.truncated(FieldIDLength,
+				st.offset+int64(headerLen-1))
+		default:
+			return 0, r.truncated(FieldPayloadLength, st.offset+2)
+		}
+	}
+	r.unmarshalHeader(buf[:headerLen])
+
+	// Make sure the declared lengths fit before using them.
+	available := uint64(len(buf) - headerLen)
+	if available < r.bodyLen() {
+		for _, f := range []string{FieldType, FieldID, FieldPayload} {
+			end := uint64(r.fieldOffset(f)) + r.fieldLen(f)
+			if uint64(len(buf)) < end {
+				return 0, r.truncated(f,
+					st.offset+int64(r.fieldOffset(f)))
+			}
+		}
+	}
+	rLen = headerLen + int(r.bodyLen())
+	// cap the body so that appending to the payload does not
+	// overwrite the input.
+	body := buf[headerLen:rLen:rLen]
+	if !st.aliasInput() {
+		body = make([]byte, r.bodyLen())
+		copy(body, buf[headerLen:rLen])
 	}
-	r.Payload = getBytes(bytesBuf, int(r.PayloadLength))
+	r.unmarshalBody(body)
 
-	rLen = len(buf) - bytesBuf.Len()
-	err = r.Check()
+	err = r.check(st)
 	if err != nil {
-		return rLen, err
+		return rLen, r.locate(err, st.offset)
 	}
 	return rLen, nil
 }
 
-// Marshal returns the byte representation of a Record, or an error
-// if something went wrong
-func (r *recordChunk) Marshal() ([]byte, error) {
-	err := r.Check()
-	if err != nil {
-		return nil, err
+func (r *recordChunk) truncated(field string, offset int64) error {
+	err := newParseError(ErrTruncated, field)
+	err.Offset = offset
+	return err
+}
+
+// chunkHeaderLen returns the length of the header of a chunk (flags,
+// TYPE_LENGTH, PAYLOAD_LENGTH and ID_LENGTH fields) from its first byte.
+func chunkHeaderLen(firstByte byte) int {
+	l := 2 // flags + TYPE_LENGTH
+
+	// Short records use a single byte for PAYLOAD_LENGTH
+	if (firstByte >> 4 & 0x1) == 1 {
+		l++
+	} else {
+		l += 4
+	}
+	// ID_LENGTH is only present when the IL flag is set
+	if (firstByte >> 3 & 0x1) == 1 {
+		l++
+	}
+	return l
+}
+
+// unmarshalHeader sets the length fields of the chunk from a header, which
+// must be chunkHeaderLen() bytes long. The flags must have been set already.
+func (r *recordChunk) unmarshalHeader(header []byte) {
+	r.TypeLength = header[1]
+	i := 2
+	if r.SR { //This is a short record
+		r.PayloadLength = uint64(header[i])
+		i++
+	} else { // Regular record
+		r.PayloadLength = bytesToUint64(header[i : i+4])
+		i += 4
 	}
-	var buffer bytes.Buffer
+	if r.IL {
+		r.IDLength = header[i]
+	}
+}
+
+func (r *recordChunk) unmarshalFlags(firstByte byte) {
+	r.MB = (firstByte >> 7 & 0x1) == 1
+	r.ME = (firstByte >> 6 & 0x1) == 1
+	r.CF = (firstByte >> 5 & 0x1) == 1
+	r.SR = (firstByte >> 4 & 0x1) == 1
+	r.IL = (firstByte >> 3 & 0x1) == 1
+	r.TNF = TNF(firstByte & 0x7)
+}
+
+// flags returns the first byte of the chunk, with the MB, ME, CF, SR, IL
+// flags and the TNF.
+func (r *recordChunk) flags() byte {
 	firstByte := byte(0)
 	if r.MB {
 		firstByte |= 0x1 << 7
@@ -184,59 +315,224 @@ func (r *recordChunk) Marshal() ([]byte, error) {
 	if r.IL {
 		firstByte |= 0x1 << 3
 	}
-	firstByte |= (r.TNF & 0x7) //Last 3 bits are from TNF
-	buffer.WriteByte(firstByte)
+	firstByte |= byte(r.TNF & 0x7) //Last 3 bits are from TNF
+	return firstByte
+}
+
+// fieldOffset returns the offset of the given field from the start of the
+// chunk, according to its flags and length fields.
+func (r *recordChunk) fieldOffset(field string) int {
+	headerLen := chunkHeaderLen(r.flags())
+	switch field {
+	case FieldTypeLength:
+		return 1
+	case FieldPayloadLength:
+		return 2
+	case FieldIDLength:
+		return headerLen - 1
+	case FieldType:
+		return headerLen
+	case FieldID:
+		return headerLen + int(r.TypeLength)
+	case FieldPayload:
+		if r.IL {
+			return headerLen + int(r.TypeLength) + int(r.IDLength)
+		}
+		return headerLen + int(r.TypeLength)
+	default:
+		return 0
+	}
+}
+
+// fieldLen returns the declared length of the Type, ID or Payload fields.
+func (r *recordChunk) fieldLen(field string) uint64 {
+	switch field {
+	case FieldType:
+		return uint64(r.TypeLength)
+	case FieldID:
+		if r.IL {
+			return uint64(r.IDLength)
+		}
+		return 0
+	case FieldPayload:
+		return r.PayloadLength
+	default:
+		return 0
+	}
+}
+
+// bodyLen returns the number of bytes following the header of the chunk,
+// as declared by its length fields.
+func (r *recordChunk) bodyLen() uint64 {
+	l := uint64(r.TypeLength) + r.PayloadLength
+	if r.IL {
+		l += uint64(r.IDLength)
+	}
+	return l
+}
+
+// encodedLen returns the length of the Marshaling of the chunk, which
+// includes the actual type, ID and payload fields.
+func (r *recordChunk) encodedLen() int {
+	l := chunkHeaderLen(r.flags()) + len(r.Payload)
+	if r.TypeLength > 0 {
+		l += len(r.Type)
+	}
+	if r.IL && r.IDLength > 0 {
+		l += len(r.ID)
+	}
+	return l
+}
+
+// unmarshalBody sets the Type, ID and Payload fields of the chunk
+// from a body of bodyLen() bytes.
+func (r *recordChunk) unmarshalBody(body []byte) {
+	i := uint64(r.TypeLength)
+	r.Type = string(body[:i])
+	if r.IL {
+		r.ID = string(body[i : i+uint64(r.IDLength)])
+		i += uint64(r.IDLength)
+	}
+	r.Payload = body[i:]
+}
+
+// chunkSource is implemented by the inputs we read record chunks from
+// when parsing Records and Messages.
+type chunkSource interface {
+	// more returns whether there is any data left to read.
+	more() (bool, error)
+	// next reads the next chunk. It returns io.EOF if there was no
+	// data left to read.
+	next(st *decodeState) (*recordChunk, error)
+	// unread makes next() return the given chunks again. They must
+	// be the last ones read, and warnings those produced when reading them.
+	unread(chunks []*recordChunk, warnings []*ParseError)
+}
+
+// sliceChunkSource reads chunks from a byte slice, keeping track of the
+// number of bytes consumed.
+type sliceChunkSource struct {
+	buf []byte
+	off int
+}
+
+func (s *sliceChunkSource) more() (bool, error) {
+	return s.off < len(s.buf), nil
+}
+
+func (s *sliceChunkSource) next(st *decodeState) (*recordChunk, error) {
+	if s.off >= len(s.buf) {
+		return nil, io.EOF
+	}
+	chunk := &recordChunk{}
+	st.offset = int64(s.off)
+	n, err := chunk.unmarshal(s.buf[s.off:], st)
+	chunk.offset = int64(s.off)
+	s.off += n
+	return chunk, err
+}
+
+// unread rewinds to the first of the chunks, which will be parsed again.
+func (s *sliceChunkSource) unread(chunks []*recordChunk, warnings []*ParseError) {
+	if len(chunks) > 0 {
+		s.off = int(chunks[0].offset)
+	}
+}
+
+// Marshal returns the byte representation of a Record, or an error
+// if something went wrong
+func (r *recordChunk) Marshal() ([]byte, error) {
+	return r.appendMarshal(nil)
+}
+
+// appendMarshal appends the byte representation of the recordChunk to
+// dst.
+func (r *recordChunk) appendMarshal(dst []byte) ([]byte, error) {
+	err := r.Check()
+	if err != nil {
+		return dst, err
+	}
+	dst = r.appendHeader(dst)
+	return append(dst, r.Payload...), nil
+}
+
+// appendHeader appends everything that comes before the payload in a chunk:
+// the flags, the length fields, the type and the ID.
+func (r *recordChunk) appendHeader(dst []byte) []byte {
+	firstByte := r.flags()
+	dst = append(dst, firstByte)
 	// TypeLength byte
-	buffer.WriteByte(r.TypeLength)
+	dst = append(dst, r.TypeLength)
 
 	// Payload Length byte (for SR) or 4 bytes for the regular case
 	if r.SR {
-		buffer.WriteByte(byte(r.PayloadLength))
+		dst = append(dst, byte(r.PayloadLength))
 	} else {
-		buffer.Write(uint64ToBytes(r.PayloadLength, 4))
+		l := r.PayloadLength
+		dst = append(dst, byte(l>>24), byte(l>>16), byte(l>>8), byte(l))
 	}
 
 	// ID Length byte if we are meant to have it
 	if r.IL {
-		buffer.WriteByte(r.IDLength)
+		dst = append(dst, r.IDLength)
 	}
 
 	// Write the type bytes if we have something
 	if r.TypeLength > 0 {
-		buffer.Write([]byte(r.Type))
+		dst = append(dst, r.Type...)
 	}
 
 	// Write the ID bytes if we have something
 	if r.IL && r.IDLength > 0 {
-		buffer.Write([]byte(r.ID))
+		dst = append(dst, r.ID...)
 	}
-
-	buffer.Write(r.Payload)
-	return buffer.Bytes(), nil
+	return dst
 }
 
 // Check verifies that fields in this chunk are not in violation of the spec.
+// Errors are of type *ParseError.
 func (r *recordChunk) Check() error {
+	return r.check(nil)
+}
+
+// check verifies the chunk, allowing some violations to be downgraded to
+// warnings when decoding leniently.
+func (r *recordChunk) check(st *decodeState) error {
 	// If the TNF value is 0x00, the TYPE_LENGTH, ID_LENGTH,
 	// and PAYLOAD_LENGTH fields MUST be zero and the TYPE, ID,
 	// and PAYLOAD fields MUST be omitted from the record.
-	if r.TNF == Empty && (r.TypeLength > 0 ||
-		r.IDLength > 0 || r.PayloadLength > 0) {
-		return errors.New("Record.check: " +
-			"Empty record TNF but not empty fields")
+	if r.TNF == Empty {
+		switch {
+		case r.TypeLength > 0:
+			return r.checkError(ErrNotEmpty, FieldTypeLength)
+		case r.IDLength > 0:
+			return r.checkError(ErrNotEmpty, FieldIDLength)
+		case r.PayloadLength > 0:
+			return r.checkError(ErrNotEmpty, FieldPayloadLength)
+		}
 	}
 	// If the TNF value is 0x05 or 0x06 (Unknown/Unchanged),
 	// the TYPE_LENGTH field MUST be 0 and the TYPE
 	// field MUST be omitted from the NDEF record.
 	if (r.TNF == Unknown || r.TNF == Unchanged) && r.TypeLength > 0 {
-		return errors.New("Record.check: " +
-			"This TNF does not support a Type field")
+		return r.checkError(ErrTypeNotAllowed, FieldTypeLength)
+	}
+
+	// The length fields cannot represent longer values.
+	if len(r.Type) > 255 {
+		return r.checkError(ErrTypeTooLong, FieldType)
+	}
+	if r.IL && len(r.ID) > 255 {
+		return r.checkError(ErrIDTooLong, FieldID)
+	}
+	if r.PayloadLength > maxPayloadLength ||
+		(r.SR && r.PayloadLength > 255) {
+		return r.checkError(ErrPayloadTooLong, FieldPayloadLength)
 	}
 
 	// The TNF value MUST NOT be 0x07.
 	if r.TNF == Reserved {
-		return errors.New("Record.check: " +
-			"The TNF cannot be Reserved, that value is reserved.")
+		return r.checkError(ErrReservedTNF, FieldFlags)
 	}
 
 	// NFC Record Type Definition 3.4:
@@ -249,10 +545,12 @@ func (r *recordChunk) Check() error {
 		typeString := string(r.Type)
 		for _, rune := range typeString {
 			if rune < 32 || rune > 126 {
-				return errors.New("Record.check(): " +
-					"Record type names SHALL " +
-					"be formed of characters from of the " +
-					"US ASCII [ASCII] character set")
+				err := r.checkError(ErrNonASCIIType, FieldType)
+				if !st.lenient() {
+					return err
+				}
+				st.warn(r.locate(err, st.offset))
+				break
 			}
 		}
 	}
@@ -260,10 +558,28 @@ func (r *recordChunk) Check() error {
 	if r.IL && r.IDLength > 0 {
 		for _, rune := range r.ID {
 			if rune < 32 || rune > 126 {
-				return errors.New("Record.check(): " +
-					"ID must use ASCII characters")
+				err := r.checkError(ErrNonASCIIID, FieldID)
+				if !st.lenient() {
+					return err
+				}
+				st.warn(r.locate(err, st.offset))
+				break
 			}
 		}
 	}
 	return nil
 }
+
+func (r *recordChunk) checkError(err error, field string) error {
+	return newParseError(err, field)
+}
+
+// locate sets the offset of a *ParseError affecting this chunk to that of
+// the offending field, given the offset of the chunk itself.
+func (r *recordChunk) locate(err error, chunkOffset int64) error {
+	var perr *ParseError
+	if errors.As(err, &perr) && perr.Offset < 0 {
+		perr.Offset = chunkOffset + int64(r.fieldOffset(perr.Field))
+	}
+	return err
+}
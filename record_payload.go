@@ -17,15 +17,6 @@
 
 package ndef
 
-import (
-	"github.com/hsanjuan/go-ndef/types/absoluteuri"
-	"github.com/hsanjuan/go-ndef/types/ext"
-	"github.com/hsanjuan/go-ndef/types/generic"
-	"github.com/hsanjuan/go-ndef/types/media"
-	"github.com/hsanjuan/go-ndef/types/wkt/text"
-	"github.com/hsanjuan/go-ndef/types/wkt/uri"
-)
-
 // The RecordPayload interface should be implemented by supported
 // NDEF Record types. It ensures that we have a way to interpret payloads
 // into printable information and to produce NDEF Record payloads for a given
@@ -37,35 +28,56 @@ type RecordPayload interface {
 	Marshal() []byte
 	// Provides de-serialization for the Payload
 	Unmarshal(buf []byte)
-	// Returns a string indetifying the type of this payload
+	// Returns a string indetifying the type of this payload, like
+	// "urn:nfc:wkt:T" or "text/plain". It is meant to be read, and it is
+	// not always the type URN of the record (see Record.TypeURN).
 	Type() string
 	// Returns the length of the Payload (serialized)
 	Len() int
 }
 
-func makeRecordPayload(tnf byte, rtype string, payload []byte) RecordPayload {
-	var r RecordPayload
-	switch tnf {
-	case NFCForumWellKnownType:
-		switch rtype {
-		case "U":
-			r = new(uri.Payload)
-		case "T":
-			r = new(text.Payload)
-		case "Sp":
-			r = new(SmartPosterPayload)
-		default:
-			r = new(generic.Payload)
-		}
-	case MediaType:
-		r = media.New(rtype, nil)
-	case NFCForumExternalType:
-		r = ext.New(rtype, nil)
-	case AbsoluteURI:
-		r = absoluteuri.New(rtype, nil)
-	default:
-		r = new(generic.Payload)
+// RecordPayloadV2 extends RecordPayload with a way to find out about
+// malformed payloads. All the payload types in this module implement it.
+type RecordPayloadV2 interface {
+	RecordPayload
+	// Parses the Payload, returning an error if it is malformed.
+	// The Payload is filled as far as possible anyways.
+	Decode(buf []byte) error
+}
+
+// AsRecordPayloadV2 returns a RecordPayloadV2 for the given RecordPayload.
+// Payloads which do not implement it are wrapped so that Decode() calls
+// Unmarshal() and never fails.
+func AsRecordPayloadV2(p RecordPayload) RecordPayloadV2 {
+	if v2, ok := p.(RecordPayloadV2); ok {
+		return v2
+	}
+	return legacyPayload{p}
+}
+
+type legacyPayload struct {
+	RecordPayload
+}
+
+func (p legacyPayload) Decode(buf []byte) error {
+	p.Unmarshal(buf)
+	return nil
+}
+
+// OptionsDecoder can be implemented by payloads which hold NDEF data, like
+// Smart Posters, so that it is decoded with the same DecodeOptions as the
+// record holding it. Record.Payload() calls DecodeWithOptions instead of
+// Decode, with the options returned by DecodeOptions.WithParent for the
+// record, which are meant to be passed on to DecodeOptions.Unmarshal.
+type OptionsDecoder interface {
+	DecodeWithOptions(buf []byte, opts DecodeOptions) error
+}
+
+func makeRecordPayload(tnf TNF, rtype string, payload []byte, opts DecodeOptions) (RecordPayload, error) {
+	r := opts.registry().newPayload(tnf, rtype, opts.parent)
+	if od, ok := r.(OptionsDecoder); ok {
+		return r, od.DecodeWithOptions(payload, opts.WithParent(tnf, rtype))
 	}
-	r.Unmarshal(payload)
-	return r
+	err := AsRecordPayloadV2(r).Decode(payload)
+	return r, err
 }
@@ -0,0 +1,72 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/wkt/text"
+)
+
+type legacyTestPayload struct {
+	buf []byte
+}
+
+func (p *legacyTestPayload) String() string     { return string(p.buf) }
+func (p *legacyTestPayload) Type() string       { return "legacy" }
+func (p *legacyTestPayload) Marshal() []byte    { return p.buf }
+func (p *legacyTestPayload) Unmarshal(b []byte) { p.buf = b }
+func (p *legacyTestPayload) Len() int           { return len(p.buf) }
+
+func TestAsRecordPayloadV2(t *testing.T) {
+	tx := new(text.Payload)
+	if AsRecordPayloadV2(tx) != RecordPayloadV2(tx) {
+		t.Error("V2 payloads should be returned as they are")
+	}
+
+	legacy := new(legacyTestPayload)
+	err := AsRecordPayloadV2(legacy).Decode([]byte("abc"))
+	if err != nil {
+		t.Error(err)
+	}
+	if legacy.String() != "abc" {
+		t.Error("Decode should have called Unmarshal")
+	}
+}
+
+func TestRecordPayloadError(t *testing.T) {
+	r := NewRecord(NFCForumWellKnownType, "T", "", nil)
+	r.chunks[0].Payload = []byte{0x05, 'e', 'n'}
+	r.chunks[0].PayloadLength = 3
+	pl, err := r.Payload()
+	if !errors.Is(err, text.ErrShortLanguage) {
+		t.Error("expected text.ErrShortLanguage, got", err)
+	}
+	if pl == nil || pl.(*text.Payload).Language != "en" {
+		t.Error("the partially decoded payload should be returned")
+	}
+
+	r = NewRecord(NFCForumWellKnownType, "Sp", "", nil)
+	r.chunks[0].Payload = []byte{0x11, 0x01, 0x01, 'U'}
+	r.chunks[0].PayloadLength = 4
+	_, err = r.Payload()
+	if !errors.Is(err, ErrTruncated) {
+		t.Error("expected ErrTruncated, got", err)
+	}
+}
@@ -19,9 +19,14 @@ package ndef
 
 import (
 	"bytes"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/hsanjuan/go-ndef/types/absoluteuri"
+	"github.com/hsanjuan/go-ndef/types/ext"
 	"github.com/hsanjuan/go-ndef/types/generic"
+	"github.com/hsanjuan/go-ndef/types/media"
 )
 
 func TestRecordMarshalUnmarshal(t *testing.T) {
@@ -30,7 +35,7 @@ func TestRecordMarshalUnmarshal(t *testing.T) {
 		NFCForumExternalType,
 		"test",
 		"#ab",
-		&generic.Payload{[]byte("abc")},
+		&generic.Payload{Payload: []byte("abc")},
 	)
 
 	rBytes, err := r.Marshal()
@@ -56,20 +61,20 @@ func TestRecordMarshalUnmarshal(t *testing.T) {
 func TestRecordString(t *testing.T) {
 	// Just test we are not crashing
 	type args struct {
-		TNF     byte
+		TNF     TNF
 		Type    string
 		ID      string
 		Payload RecordPayload
 	}
 
 	tcs := []args{
-		args{NFCForumWellKnownType, "X", "#ab", &generic.Payload{[]byte("abc")}},
+		args{NFCForumWellKnownType, "X", "#ab", &generic.Payload{Payload: []byte("abc")}},
 		args{Empty, "", "", nil},
-		args{MediaType, "image/jpeg", "", &generic.Payload{[]byte("\x03abc")}},
-		args{AbsoluteURI, "http://resource", "#ab", &generic.Payload{[]byte("")}},
-		args{NFCForumExternalType, "T", "#ab", &generic.Payload{[]byte("abc")}},
-		args{Unknown, "", "", &generic.Payload{[]byte("abc")}},
-		args{Unchanged, "", "", &generic.Payload{[]byte("abc")}},
+		args{MediaType, "image/jpeg", "", &generic.Payload{Payload: []byte("\x03abc")}},
+		args{AbsoluteURI, "http://resource", "#ab", &generic.Payload{Payload: []byte("")}},
+		args{NFCForumExternalType, "T", "#ab", &generic.Payload{Payload: []byte("abc")}},
+		args{Unknown, "", "", &generic.Payload{Payload: []byte("abc")}},
+		args{Unchanged, "", "", &generic.Payload{Payload: []byte("abc")}},
 	}
 
 	m := NewTextRecord("abc", "en")
@@ -85,14 +90,14 @@ func TestRecordString(t *testing.T) {
 }
 
 func TestRecordBadChunksTest(t *testing.T) {
-	cases := []struct{ Expected string }{
-		{eNOCHUNKS},
-		{eFIRSTCHUNKED},
-		{eLASTCHUNKED},
-		{eCFMISSING},
-		{eBADIL},
-		{eBADTYPELENGTH},
-		{eBADTNF},
+	cases := []struct{ Expected error }{
+		{ErrNoChunks},
+		{ErrSingleChunked},
+		{ErrLastChunked},
+		{ErrMissingChunkFlag},
+		{ErrBadIL},
+		{ErrBadTypeLength},
+		{ErrBadTNF},
 	}
 
 	errs := []error{}
@@ -204,7 +209,7 @@ func TestRecordBadChunksTest(t *testing.T) {
 		t.Logf("Expected: %s...", cases[i].Expected)
 		if err == nil {
 			t.Error("Test didn't fail as expected")
-		} else if e := cases[i].Expected; err.Error() != e {
+		} else if e := cases[i].Expected; !errors.Is(err, e) {
 			t.Errorf("Test failed unexpectedly because: %s.", err)
 		} else {
 			t.Log("Ok!")
@@ -280,3 +285,248 @@ func TestNDEFGoodrecordChunkTest(t *testing.T) {
 		t.Error("Payload is not what we would expect!")
 	}
 }
+
+func TestRecordAppendMarshal(t *testing.T) {
+	r := NewTextRecord("hello", "en")
+	expected, err := r.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := r.AppendMarshal([]byte{0xAA})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, append([]byte{0xAA}, expected...)) {
+		t.Error("bad append")
+	}
+}
+
+func TestRecordTypeValidation(t *testing.T) {
+	type testcase struct {
+		tnf      TNF
+		bad      string
+		good     string
+		expected error
+	}
+	tcs := []testcase{
+		{NFCForumExternalType, "test", "example.com:test", ErrBadExternalType},
+		{MediaType, "vcrd", "text/vcard; charset=utf-8", ErrBadMediaType},
+		{AbsoluteURI, "a/bc", "https://example.com/type", ErrBadAbsoluteURIType},
+	}
+
+	for _, tc := range tcs {
+		// Malformed types are decoded as they are.
+		r := NewRecord(tc.tnf, tc.bad, "", generic.New([]byte("x")))
+		rBytes, err := r.Marshal()
+		if err != nil {
+			t.Fatalf("%q: %s", tc.bad, err)
+		}
+		var decoded Record
+		if _, err := decoded.Unmarshal(rBytes); err != nil || decoded.Type() != tc.bad {
+			t.Errorf("%q: decoding failed: %v", tc.bad, err)
+		}
+
+		if err := CheckType(tc.tnf, tc.bad); err != tc.expected {
+			t.Errorf("%q: expected %s, got %v", tc.bad, tc.expected, err)
+		}
+		if _, err := NewCheckedRecord(tc.tnf, tc.bad, "", nil); !errors.Is(err, tc.expected) {
+			t.Errorf("%q: NewCheckedRecord should fail: %v", tc.bad, err)
+		}
+		if _, err := (MarshalOptions{CheckTypes: true}).MarshalRecord(r); !errors.Is(err, tc.expected) {
+			t.Errorf("%q: marshaling should fail: %v", tc.bad, err)
+		}
+
+		r, err = NewCheckedRecord(tc.tnf, tc.good, "", nil)
+		if err != nil {
+			t.Errorf("%q: %s", tc.good, err)
+			continue
+		}
+		if _, err := (MarshalOptions{CheckTypes: true}).MarshalRecord(r); err != nil {
+			t.Errorf("%q: %s", tc.good, err)
+		}
+	}
+}
+
+func TestNewCheckedMediaRecord(t *testing.T) {
+	bad := map[string]error{
+		"image":              media.ErrNoSubtype,
+		"image/":             media.ErrNoSubtype,
+		"image/png; charset": media.ErrBadParameter,
+		"image/png; a=\"b":   media.ErrBadParameter,
+		"image/png; a=b; =c": media.ErrBadParameter,
+	}
+	for typ, expected := range bad {
+		if _, err := NewCheckedMediaRecord(typ, nil); err != expected {
+			t.Errorf("%q: expected %s, got %v", typ, expected, err)
+		}
+	}
+
+	r, err := NewCheckedMediaRecord("image/png; a=\"b c\"", []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Equal(NewMediaRecord("image/png; a=\"b c\"", []byte{1})) {
+		t.Error("unexpected record")
+	}
+}
+
+func TestNewCheckedAbsoluteURIRecord(t *testing.T) {
+	if _, err := NewCheckedAbsoluteURIRecord("/type", nil); err != absoluteuri.ErrNotAbsolute {
+		t.Error("expected an error for a relative URI:", err)
+	}
+	r, err := NewCheckedAbsoluteURIRecord("https://example.com/type", []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TNF() != AbsoluteURI || r.Type() != "https://example.com/type" {
+		t.Error("unexpected record")
+	}
+}
+
+func TestNewCheckedExternalRecord(t *testing.T) {
+	if _, err := NewCheckedExternalRecord("test", nil); err != ext.ErrNoDomain {
+		t.Error("expected an error for a type without domain:", err)
+	}
+	r, err := NewCheckedExternalRecord("example.com:test", []byte{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.TNF() != NFCForumExternalType || r.Type() != "example.com:test" {
+		t.Error("unexpected record")
+	}
+}
+
+func TestRecordSetTypeAnyOrder(t *testing.T) {
+	r := NewMediaRecord("a/b", nil)
+	if err := r.SetTNF(NFCForumExternalType); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetType("example.com:t"); err != nil {
+		t.Fatal(err)
+	}
+
+	r2 := NewMediaRecord("a/b", nil)
+	if err := r2.SetType("example.com:t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r2.SetTNF(NFCForumExternalType); err != nil {
+		t.Fatal(err)
+	}
+	if !r.Equal(r2) || r.checkType() != nil {
+		t.Error("both orders should give the same valid record")
+	}
+}
+
+func TestRecordSetters(t *testing.T) {
+	r := NewURIRecord("https://example.com")
+	if err := r.SetID("uri"); err != nil {
+		t.Fatal(err)
+	}
+	chunk := r.chunks[0]
+	if r.ID() != "uri" || !chunk.IL || chunk.IDLength != 3 {
+		t.Error("bad ID fields")
+	}
+	if err := r.SetID(""); err != nil {
+		t.Fatal(err)
+	}
+	if r.chunks[0].IL || r.chunks[0].IDLength != 0 {
+		t.Error("ID should have been removed")
+	}
+
+	if err := r.SetTNF(NFCForumExternalType); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetType("example.com:a"); err != nil {
+		t.Fatal(err)
+	}
+	if r.TNF() != NFCForumExternalType || r.Type() != "example.com:a" ||
+		r.chunks[0].TypeLength != 13 {
+		t.Error("bad TNF and type fields")
+	}
+
+	if err := r.SetPayloadBytes(make([]byte, 300)); err != nil {
+		t.Fatal(err)
+	}
+	if r.chunks[0].SR || r.chunks[0].PayloadLength != 300 {
+		t.Error("bad payload length fields")
+	}
+	if err := r.SetPayload(generic.New([]byte{1})); err != nil {
+		t.Fatal(err)
+	}
+	if !r.chunks[0].SR || r.chunks[0].PayloadLength != 1 {
+		t.Error("bad payload length fields")
+	}
+	if !r.MB() || !r.ME() {
+		t.Error("MB and ME should be preserved")
+	}
+
+	// invalid changes leave the record untouched
+	if err := r.SetTNF(Unchanged); err != ErrInvalidTNF {
+		t.Error("expected ErrInvalidTNF, got", err)
+	}
+	if err := r.SetTNF(Empty); !errors.Is(err, ErrNotEmpty) {
+		t.Error("expected ErrNotEmpty, got", err)
+	}
+	if err := r.SetType(strings.Repeat("a", 256)); !errors.Is(err, ErrTypeTooLong) {
+		t.Error("expected ErrTypeTooLong, got", err)
+	}
+	if r.TNF() != NFCForumExternalType || r.Type() != "example.com:a" {
+		t.Error("record should not have been modified")
+	}
+
+	// a record built from scratch
+	r = &Record{}
+	if err := r.SetTNF(MediaType); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetType("text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetPayloadBytes([]byte("hi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Marshal(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecordSetPayloadChunked(t *testing.T) {
+	r, err := NewChunkedRecord(MediaType, "a/b", "id", generic.New(make([]byte, 10)), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetPayloadBytes(make([]byte, 13)); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.chunks) != 4 || r.chunks[3].PayloadLength != 1 {
+		t.Error("payload should have been split in chunks of 4 bytes")
+	}
+	if r.ID() != "id" || r.Type() != "a/b" {
+		t.Error("type and ID should be preserved")
+	}
+	if err := r.check(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRecordInspectMedia(t *testing.T) {
+	r := NewMediaRecord("application/json", []byte(`{"a":1}`))
+	if !strings.Contains(r.Inspect(), "Payload: {\n  \"a\": 1\n}") {
+		t.Error("the payload should be pretty-printed:", r.Inspect())
+	}
+}
+
+func TestRecordStringMalformed(t *testing.T) {
+	// RFU bit set in the status byte
+	r := NewRecord(NFCForumWellKnownType, "T", "", generic.New([]byte("\x42enhi")))
+	expected := "urn:nfc:wkt:T:hi (text: reserved bit set in status byte)"
+	if s := r.String(); s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+	if s := r.Inspect(); !strings.HasSuffix(s, "\nPayload Error: text: reserved bit set in status byte") {
+		t.Error("the payload error should be shown:", s)
+	}
+	if s := (&Record{}).String(); s != "empty record" {
+		t.Error("unexpected string for an empty record:", s)
+	}
+}
@@ -0,0 +1,223 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hsanjuan/go-ndef/types/absoluteuri"
+	"github.com/hsanjuan/go-ndef/types/ext"
+	"github.com/hsanjuan/go-ndef/types/generic"
+	"github.com/hsanjuan/go-ndef/types/media"
+	"github.com/hsanjuan/go-ndef/types/wkt/action"
+	"github.com/hsanjuan/go-ndef/types/wkt/text"
+	"github.com/hsanjuan/go-ndef/types/wkt/uri"
+)
+
+// PayloadFactory returns a new RecordPayload for a record with the given
+// TNF and type. The payload bytes are decoded into it afterwards.
+type PayloadFactory func(tnf TNF, typ string) RecordPayload
+
+// A Registry maps record types to the PayloadFactory which provides the
+// RecordPayload for them. It is safe for concurrent use.
+//
+// Local NFC Forum Well-Known type names, like "act" in Smart Posters,
+// only have meaning inside the payload of their parent record. They are
+// registered for a given parent with RegisterLocal, and records with
+// local types are given a generic.Payload anywhere else. DefaultRegistry
+// registers the "act" records of Smart Posters this way.
+//
+// Types are matched following the rules of each TNF: NFC Forum
+// External Types and Media Types are case-insensitive, and parameters
+// in Media Types (i.e. "; charset=utf-8") are ignored. Absolute URI types
+// are compared once normalized (see absoluteuri.NormalizeType). Other
+// types are matched exactly.
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[registryKey]PayloadFactory
+}
+
+type registryKey struct {
+	tnf    TNF
+	typ    string
+	parent string // Well-Known type of the parent of local types
+}
+
+// DefaultRegistry holds the payload types supported by this module. It
+// is used when decoding unless another Registry is given in the
+// DecodeOptions.
+var DefaultRegistry = newDefaultRegistry()
+
+// NewRegistry returns an empty Registry. Use DefaultRegistry.Clone() to
+// obtain a Registry which extends the built-in payload types.
+func NewRegistry() *Registry {
+	return &Registry{
+		factories: make(map[registryKey]PayloadFactory),
+	}
+}
+
+func newDefaultRegistry() *Registry {
+	reg := NewRegistry()
+	reg.Register(NFCForumWellKnownType, "U", func(TNF, string) RecordPayload {
+		return new(uri.Payload)
+	})
+	reg.Register(NFCForumWellKnownType, "T", func(TNF, string) RecordPayload {
+		return new(text.Payload)
+	})
+	reg.Register(NFCForumWellKnownType, "Sp", func(TNF, string) RecordPayload {
+		return new(SmartPosterPayload)
+	})
+	reg.RegisterLocal("Sp", "act", func(TNF, string) RecordPayload {
+		return new(action.Payload)
+	})
+	reg.Register(MediaType, "", func(_ TNF, typ string) RecordPayload {
+		return media.New(typ, nil)
+	})
+	reg.Register(NFCForumExternalType, "", func(_ TNF, typ string) RecordPayload {
+		return ext.New(typ, nil)
+	})
+	reg.Register(AbsoluteURI, "", func(_ TNF, typ string) RecordPayload {
+		return absoluteuri.New(typ, nil)
+	})
+	return reg
+}
+
+// RegisterPayloadType registers a PayloadFactory for the given TNF and
+// type in the DefaultRegistry. See Registry.Register.
+func RegisterPayloadType(tnf TNF, typ string, f PayloadFactory) {
+	DefaultRegistry.Register(tnf, typ, f)
+}
+
+// Register sets the PayloadFactory for records with the given TNF and
+// type, replacing any previous one. An empty type registers a fallback
+// for all the types with that TNF which are not registered on their own.
+// A nil PayloadFactory removes the registration.
+//
+// Local Well-Known type names are not resolved with these registrations.
+// Use RegisterLocal for them.
+func (reg *Registry) Register(tnf TNF, typ string, f PayloadFactory) {
+	reg.register(registryKey{tnf: tnf, typ: normalizeType(tnf, typ)}, f)
+}
+
+// RegisterLocal sets the PayloadFactory for records with the local
+// Well-Known type name typ (see IsLocalType) found in the payload of
+// records of the Well-Known type parent, i.e. "act" in "Sp". An empty
+// type registers a fallback for all the local types in parent. A nil
+// PayloadFactory removes the registration.
+func (reg *Registry) RegisterLocal(parent, typ string, f PayloadFactory) {
+	reg.register(registryKey{NFCForumWellKnownType, typ, parent}, f)
+}
+
+func (reg *Registry) register(key registryKey, f PayloadFactory) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	if f == nil {
+		delete(reg.factories, key)
+		return
+	}
+	reg.factories[key] = f
+}
+
+// Lookup returns the PayloadFactory for records with the given TNF and
+// type, the fallback for the TNF when the type is not registered, or nil.
+// It always returns nil for local Well-Known type names: see LookupLocal.
+func (reg *Registry) Lookup(tnf TNF, typ string) PayloadFactory {
+	if tnf == NFCForumWellKnownType && IsLocalType(typ) {
+		return nil
+	}
+	return reg.lookup(registryKey{tnf: tnf, typ: normalizeType(tnf, typ)})
+}
+
+// LookupLocal returns the PayloadFactory for records with the local
+// Well-Known type name typ in the payload of records of the Well-Known
+// type parent, the fallback for the local types of parent when the type
+// is not registered, or nil.
+func (reg *Registry) LookupLocal(parent, typ string) PayloadFactory {
+	if parent == "" || !IsLocalType(typ) {
+		return nil
+	}
+	return reg.lookup(registryKey{NFCForumWellKnownType, typ, parent})
+}
+
+func (reg *Registry) lookup(key registryKey) PayloadFactory {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	if f, ok := reg.factories[key]; ok {
+		return f
+	}
+	key.typ = ""
+	return reg.factories[key]
+}
+
+// Clone returns a copy of the Registry, which can be modified without
+// affecting the original.
+func (reg *Registry) Clone() *Registry {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	clone := NewRegistry()
+	for k, f := range reg.factories {
+		clone.factories[k] = f
+	}
+	return clone
+}
+
+// newPayload returns a RecordPayload for the given TNF and type, or a
+// generic.Payload when none is registered. Local types are resolved in
+// the context of the Well-Known type parent, which is empty for
+// top-level records.
+func (reg *Registry) newPayload(tnf TNF, typ, parent string) RecordPayload {
+	f := reg.Lookup(tnf, typ)
+	if tnf == NFCForumWellKnownType && IsLocalType(typ) {
+		f = reg.LookupLocal(parent, typ)
+	}
+	if f != nil {
+		if pl := f(tnf, typ); pl != nil {
+			return pl
+		}
+	}
+	return new(generic.Payload)
+}
+
+// normalizeType returns the form of the type used as registry key.
+func normalizeType(tnf TNF, typ string) string {
+	switch tnf {
+	case NFCForumExternalType:
+		return strings.ToLower(typ)
+	case MediaType:
+		if i := strings.IndexByte(typ, ';'); i >= 0 {
+			typ = typ[:i]
+		}
+		return strings.ToLower(strings.TrimSpace(typ))
+	case AbsoluteURI:
+		return absoluteuri.NormalizeType(typ)
+	default:
+		return typ
+	}
+}
+
+// IsLocalType returns true for NFC Forum Well-Known type names which are
+// local to the record they are found in, which start with a lowercase
+// letter or a number, as defined in the NFC Record Type Definition.
+func IsLocalType(typ string) bool {
+	if typ == "" {
+		return false
+	}
+	c := typ[0]
+	return (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9')
+}
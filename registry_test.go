@@ -0,0 +1,246 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/ext"
+	"github.com/hsanjuan/go-ndef/types/generic"
+	"github.com/hsanjuan/go-ndef/types/media"
+	"github.com/hsanjuan/go-ndef/types/wkt/action"
+	"github.com/hsanjuan/go-ndef/types/wkt/text"
+)
+
+type counterPayload struct {
+	generic.Payload
+}
+
+func newCounterPayload(TNF, string) RecordPayload {
+	return new(counterPayload)
+}
+
+func TestRegistryLookup(t *testing.T) {
+	reg := NewRegistry()
+	reg.Register(NFCForumExternalType, "Example.com:Counter", newCounterPayload)
+	reg.Register(MediaType, "Application/X-Counter", newCounterPayload)
+	reg.Register(NFCForumWellKnownType, "C", newCounterPayload)
+	reg.Register(AbsoluteURI, "https://example.com/counter", newCounterPayload)
+
+	tcs := []struct {
+		tnf   TNF
+		typ   string
+		found bool
+	}{
+		{NFCForumExternalType, "example.com:counter", true},
+		{NFCForumExternalType, "EXAMPLE.COM:COUNTER", true},
+		{NFCForumExternalType, "example.com:other", false},
+		{MediaType, "application/x-counter", true},
+		{MediaType, "application/x-counter; charset=utf-8", true},
+		{MediaType, "application/x-other", false},
+		{NFCForumWellKnownType, "C", true},
+		{NFCForumWellKnownType, "c", false},
+		{AbsoluteURI, "C", false},
+		{AbsoluteURI, "HTTPS://Example.com/%63ounter", true},
+		{AbsoluteURI, "https://example.com/Counter", false},
+	}
+
+	for _, tc := range tcs {
+		if found := reg.Lookup(tc.tnf, tc.typ) != nil; found != tc.found {
+			t.Errorf("%d %s: expected found=%t", tc.tnf, tc.typ, tc.found)
+		}
+	}
+
+	reg.Register(NFCForumWellKnownType, "C", nil)
+	if reg.Lookup(NFCForumWellKnownType, "C") != nil {
+		t.Error("registration should have been removed")
+	}
+}
+
+func TestRegistryFallback(t *testing.T) {
+	pl := DefaultRegistry.newPayload(NFCForumExternalType, "example.com:a", "")
+	if e, ok := pl.(*ext.Payload); !ok || e.ExtType != "example.com:a" {
+		t.Errorf("expected ext.Payload, got %T", pl)
+	}
+	pl = DefaultRegistry.newPayload(MediaType, "image/png", "")
+	if m, ok := pl.(*media.Payload); !ok || m.MimeType != "image/png" {
+		t.Errorf("expected media.Payload, got %T", pl)
+	}
+	pl = DefaultRegistry.newPayload(NFCForumWellKnownType, "unknown", "")
+	if _, ok := pl.(*generic.Payload); !ok {
+		t.Errorf("expected generic.Payload, got %T", pl)
+	}
+	pl = NewRegistry().newPayload(NFCForumWellKnownType, "T", "")
+	if _, ok := pl.(*generic.Payload); !ok {
+		t.Errorf("expected generic.Payload, got %T", pl)
+	}
+}
+
+func TestRegistryClone(t *testing.T) {
+	reg := DefaultRegistry.Clone()
+	reg.Register(NFCForumWellKnownType, "T", newCounterPayload)
+	if _, ok := DefaultRegistry.newPayload(NFCForumWellKnownType, "T", "").(*text.Payload); !ok {
+		t.Error("DefaultRegistry should not be modified")
+	}
+	if _, ok := reg.newPayload(NFCForumWellKnownType, "T", "").(*counterPayload); !ok {
+		t.Error("clone should use the new registration")
+	}
+	if reg.Lookup(NFCForumWellKnownType, "U") == nil {
+		t.Error("clone should keep the other types")
+	}
+}
+
+func TestDecodeOptionsRegistry(t *testing.T) {
+	reg := DefaultRegistry.Clone()
+	reg.Register(NFCForumExternalType, "example.com:counter", newCounterPayload)
+
+	inner := NewMessage(NFCForumExternalType, "Example.com:Counter", "", generic.New([]byte{1}))
+	sp := NewSmartPosterMessage(inner)
+	buf, err := sp.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := &Message{}
+	if _, _, err := (DecodeOptions{Registry: reg}).Unmarshal(buf, msg); err != nil {
+		t.Fatal(err)
+	}
+	pl, err := msg.Records[0].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested, err := pl.(*SmartPosterPayload).Message.Records[0].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := nested.(*counterPayload); !ok {
+		t.Errorf("expected the registered payload, got %T", nested)
+	}
+
+	RegisterPayloadType(NFCForumExternalType, "example.com:counter", newCounterPayload)
+	defer RegisterPayloadType(NFCForumExternalType, "example.com:counter", nil)
+	pl, _ = inner.Records[0].Payload()
+	if _, ok := pl.(*counterPayload); !ok {
+		t.Errorf("expected the registered payload, got %T", pl)
+	}
+}
+
+func TestRegistryLocal(t *testing.T) {
+	reg := DefaultRegistry.Clone()
+	reg.RegisterLocal("Sp", "act", newCounterPayload)
+	reg.Register(NFCForumWellKnownType, "act", newCounterPayload)
+
+	if reg.LookupLocal("Sp", "act") == nil {
+		t.Error("local type should be found in its parent")
+	}
+	if reg.LookupLocal("Hs", "act") != nil || reg.LookupLocal("", "act") != nil {
+		t.Error("local type should only be found in its parent")
+	}
+	if reg.Lookup(NFCForumWellKnownType, "act") != nil {
+		t.Error("local types should not be found at the top level")
+	}
+
+	act := NewRecord(NFCForumWellKnownType, "act", "", generic.New([]byte{0}))
+	buf, err := NewMessageFromRecords(
+		NewSmartPosterRecord(NewMessageFromRecords(act)),
+		act,
+	).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{}
+	if _, _, err := (DecodeOptions{Registry: reg}).Unmarshal(buf, msg); err != nil {
+		t.Fatal(err)
+	}
+
+	nested := msg.FindByType(NFCForumWellKnownType, "act")
+	if len(nested) != 2 {
+		t.Fatal("expected two act records, got", len(nested))
+	}
+	pl, err := nested[0].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pl.(*counterPayload); !ok {
+		t.Errorf("expected the registered payload, got %T", pl)
+	}
+	pl, err = msg.Records[1].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pl.(*generic.Payload); !ok {
+		t.Errorf("expected a generic payload at the top level, got %T", pl)
+	}
+
+	reg.RegisterLocal("Sp", "act", nil)
+	reg.RegisterLocal("Sp", "", newCounterPayload)
+	if reg.LookupLocal("Sp", "s") == nil {
+		t.Error("the fallback for the parent should be used")
+	}
+	if reg.LookupLocal("Sp", "Sp") != nil {
+		t.Error("global types are not local")
+	}
+}
+
+func TestRegistrySmartPosterAction(t *testing.T) {
+	act := NewRecord(NFCForumWellKnownType, "act", "", action.New(action.Save))
+	buf, err := NewMessageFromRecords(
+		NewSmartPosterRecord(NewMessageFromRecords(
+			NewURIRecord("https://example.com"),
+			act,
+		)),
+		act,
+	).Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := &Message{}
+	if _, err := msg.Unmarshal(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	nested := msg.FindByType(NFCForumWellKnownType, "act")
+	if len(nested) != 2 {
+		t.Fatal("expected two act records, got", len(nested))
+	}
+	pl, err := nested[0].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := pl.(*action.Payload); !ok || a.Action != action.Save {
+		t.Errorf("expected a save action, got %T %s", pl, pl)
+	}
+	pl, err = msg.Records[1].Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pl.(*generic.Payload); !ok {
+		t.Errorf("expected a generic payload at the top level, got %T", pl)
+	}
+}
+
+func TestIsLocalType(t *testing.T) {
+	for typ, local := range map[string]bool{
+		"act": true, "s": true, "0x": true,
+		"Sp": false, "T": false, "": false, ":a": false,
+	} {
+		if IsLocalType(typ) != local {
+			t.Errorf("%q: expected %t", typ, local)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Confidence tells how likely the guess made by Record.Sniff is to be
+// right.
+type Confidence int
+
+// Confidence levels, from lowest to highest.
+const (
+	ConfidenceNone Confidence = iota
+	ConfidenceLow
+	ConfidenceMedium
+	ConfidenceHigh
+)
+
+var confidenceNames = map[Confidence]string{
+	ConfidenceNone:   "none",
+	ConfidenceLow:    "low",
+	ConfidenceMedium: "medium",
+	ConfidenceHigh:   "high",
+}
+
+// String returns the name of the confidence level, i.e. "high".
+func (c Confidence) String() string {
+	if name, ok := confidenceNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("Confidence(%d)", int(c))
+}
+
+// SniffedNDEFType is returned by Record.Sniff for payloads which are
+// NDEF Messages. It is not a registered media type.
+const SniffedNDEFType = "application/x-ndef"
+
+// maxCBORDepth bounds the nesting of CBOR items when sniffing.
+const maxCBORDepth = 32
+
+// Sniff guesses the kind of content carried in the payload of the
+// record, regardless of its TNF and type. It is meant to help with
+// records of Unknown TNF, or whose type has no registered payload. It
+// returns a media type and how confident the guess is. In order, it
+// looks for:
+//
+//   - NDEF Messages (SniffedNDEFType).
+//   - File formats with well-known signatures, like "image/png", as
+//     detected by http.DetectContentType.
+//   - JSON objects and arrays ("application/json").
+//   - Well-formed CBOR maps, arrays and tags ("application/cbor").
+//   - UTF-8 text without control characters, or UTF-16 text with a
+//     byte order mark ("text/plain; charset=...").
+//
+// Payloads which match none of them are "application/octet-stream",
+// with ConfidenceNone. Empty payloads return an empty type.
+func (r *Record) Sniff() (string, Confidence) {
+	return sniff(r.payloadBytes(), r.opts)
+}
+
+func sniff(payload []byte, opts DecodeOptions) (string, Confidence) {
+	if len(payload) == 0 {
+		return "", ConfidenceNone
+	}
+
+	strict := DecodeOptions{Limits: opts.Limits}
+	n, _, err := strict.Unmarshal(payload, &Message{})
+	if err == nil && n == len(payload) {
+		return SniffedNDEFType, ConfidenceHigh
+	}
+
+	switch ct := http.DetectContentType(payload); {
+	case strings.HasPrefix(ct, "text/plain; charset=utf-16"):
+		return ct, ConfidenceMedium
+	case strings.HasPrefix(ct, "text/plain"),
+		ct == "application/octet-stream":
+	case strings.HasPrefix(ct, "text/"):
+		// HTML and XML are guessed from their first tag
+		return ct, ConfidenceMedium
+	default:
+		return ct, ConfidenceHigh
+	}
+
+	trimmed := bytes.TrimLeft(payload, " \t\r\n")
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') &&
+		json.Valid(payload) {
+		return "application/json", ConfidenceHigh
+	}
+
+	if major := payload[0] >> 5; major >= 4 && major <= 6 &&
+		cborItem(payload, 0) == len(payload) {
+		return "application/cbor", ConfidenceMedium
+	}
+
+	if isText(payload) {
+		// Very short payloads may as well be binary values.
+		if len(payload) < 4 {
+			return "text/plain; charset=utf-8", ConfidenceLow
+		}
+		return "text/plain; charset=utf-8", ConfidenceHigh
+	}
+	return "application/octet-stream", ConfidenceNone
+}
+
+// isText returns true for valid UTF-8 without control characters other
+// than whitespace.
+func isText(buf []byte) bool {
+	if !utf8.Valid(buf) {
+		return false
+	}
+	for _, r := range string(buf) {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// cborItem returns the length of the well-formed CBOR data item (RFC
+// 8949) at the start of buf, or -1 if there is none.
+func cborItem(buf []byte, depth int) int {
+	if len(buf) == 0 || depth > maxCBORDepth {
+		return -1
+	}
+	major, info := buf[0]>>5, buf[0]&0x1f
+	n := 1
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(buf) < n+size {
+			return -1
+		}
+		for _, b := range buf[n : n+size] {
+			arg = arg<<8 | uint64(b)
+		}
+		n += size
+	case info == 31:
+		return cborIndefinite(buf, major, depth)
+	default:
+		return -1
+	}
+
+	switch major {
+	case 0, 1: // integers
+		return n
+	case 2, 3: // byte and text strings
+		if arg > uint64(len(buf)-n) {
+			return -1
+		}
+		return n + int(arg)
+	case 4, 5: // arrays and maps
+		// every item takes at least one byte
+		if arg > uint64(len(buf)-n) {
+			return -1
+		}
+		items := arg
+		if major == 5 {
+			items *= 2
+		}
+		for i := uint64(0); i < items; i++ {
+			m := cborItem(buf[n:], depth+1)
+			if m < 0 {
+				return -1
+			}
+			n += m
+		}
+		return n
+	case 6: // tags
+		m := cborItem(buf[n:], depth+1)
+		if m < 0 {
+			return -1
+		}
+		return n + m
+	default: // simple values and floats
+		if info == 24 && arg < 32 {
+			return -1
+		}
+		return n
+	}
+}
+
+// cborIndefinite returns the length of the indefinite-length CBOR item
+// at the start of buf, or -1 if it is not well-formed.
+func cborIndefinite(buf []byte, major byte, depth int) int {
+	if major < 2 || major > 5 {
+		return -1
+	}
+	n, items := 1, 0
+	for {
+		if n >= len(buf) {
+			return -1
+		}
+		if buf[n] == 0xff { // break
+			if major == 5 && items%2 != 0 {
+				return -1
+			}
+			return n + 1
+		}
+		// strings are made of definite-length strings of the same type
+		if major <= 3 && (buf[n]>>5 != major || buf[n]&0x1f == 31) {
+			return -1
+		}
+		m := cborItem(buf[n:], depth+1)
+		if m < 0 {
+			return -1
+		}
+		n += m
+		items++
+	}
+}
@@ -0,0 +1,75 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hsanjuan/go-ndef/types/generic"
+)
+
+func TestSniff(t *testing.T) {
+	nested, err := NewURIMessage("https://example.com").Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcs := []struct {
+		name       string
+		payload    []byte
+		typ        string
+		confidence Confidence
+	}{
+		{"empty", nil, "", ConfidenceNone},
+		{"ndef", nested, SniffedNDEFType, ConfidenceHigh},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), "image/png", ConfidenceHigh},
+		{"html", []byte("<html><body>hi</body></html>"), "text/html; charset=utf-8", ConfidenceMedium},
+		{"utf16", []byte{0xfe, 0xff, 0, 'h', 0, 'i'}, "text/plain; charset=utf-16be", ConfidenceMedium},
+		{"json", []byte(` {"a": [1, 2]}`), "application/json", ConfidenceHigh},
+		{"json_scalar", []byte(`1234`), "text/plain; charset=utf-8", ConfidenceHigh},
+		{"cbor_map", []byte{0xa1, 0x61, 'a', 0x01}, "application/cbor", ConfidenceMedium},
+		{"cbor_indefinite", []byte{0x9f, 0x01, 0x5f, 0x41, 'a', 0xff, 0xff}, "application/cbor", ConfidenceMedium},
+		{"cbor_truncated", []byte{0xa2, 0x61, 'a', 0x01}, "application/octet-stream", ConfidenceNone},
+		{"text", []byte("héllo world\n"), "text/plain; charset=utf-8", ConfidenceHigh},
+		{"short_text", []byte("ok"), "text/plain; charset=utf-8", ConfidenceLow},
+		{"binary", []byte{0x00, 0x01, 0xfe, 0x7f}, "application/octet-stream", ConfidenceNone},
+	}
+
+	for _, tc := range tcs {
+		r := NewRecord(Unknown, "", "", generic.New(tc.payload))
+		typ, c := r.Sniff()
+		if typ != tc.typ || c != tc.confidence {
+			t.Errorf("%s: expected %q (%s), got %q (%s)",
+				tc.name, tc.typ, tc.confidence, typ, c)
+		}
+	}
+}
+
+func TestSniffInspect(t *testing.T) {
+	r := NewRecord(Unknown, "", "", generic.New([]byte(`{"a":1}`)))
+	if !strings.Contains(r.Inspect(), "Content: application/json (high confidence)") {
+		t.Error("the sniffed content should be shown:", r.Inspect())
+	}
+}
+
+func TestConfidenceString(t *testing.T) {
+	if ConfidenceMedium.String() != "medium" || Confidence(9).String() != "Confidence(9)" {
+		t.Error("unexpected confidence names")
+	}
+}
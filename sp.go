@@ -41,7 +41,7 @@ func (sp *SmartPosterPayload) String() string {
 
 // Type returns the URN for the Smart Poster type
 func (sp *SmartPosterPayload) Type() string {
-	return "urn:nfc:wkt:Sp"
+	return FormatTypeURN(NFCForumWellKnownType, "Sp")
 }
 
 // Marshal returns the bytes representing the payload of a Smart Poster.
@@ -53,12 +53,31 @@ func (sp *SmartPosterPayload) Marshal() []byte {
 
 // Unmarshal parses the SmartPosterPayload from a Smart Poster.
 func (sp *SmartPosterPayload) Unmarshal(buf []byte) {
+	sp.Decode(buf)
+}
+
+// Decode parses the SmartPosterPayload from a Smart Poster, returning
+// an error if the contained NDEF Message is not valid.
+func (sp *SmartPosterPayload) Decode(buf []byte) error {
+	opts := DecodeOptions{}.WithParent(NFCForumWellKnownType, "Sp")
+	return sp.DecodeWithOptions(buf, opts)
+}
+
+// DecodeWithOptions parses the SmartPosterPayload from a Smart Poster
+// with the given options. See OptionsDecoder.
+func (sp *SmartPosterPayload) DecodeWithOptions(buf []byte, opts DecodeOptions) error {
 	msg := &Message{}
-	msg.Unmarshal(buf)
+	_, _, err := opts.Unmarshal(buf, msg)
 	sp.Message = msg
+	return err
 }
 
-// Len returns the length of this payload in bytes.
+// Len returns the length of this payload in bytes, without marshaling
+// it. See Message.EncodedLen. As Marshal, it returns 0 when the message
+// is not valid.
 func (sp *SmartPosterPayload) Len() int {
-	return len(sp.Marshal())
+	if sp.Message.checkMarshal() != nil {
+		return 0
+	}
+	return sp.Message.EncodedLen()
 }
@@ -19,6 +19,7 @@ package ndef
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -62,4 +63,22 @@ func TestLen(t *testing.T) {
 	if l := sp.Len(); l != 18 {
 		t.Error("Unexpected length: ", l)
 	}
+	if allocs := testing.AllocsPerRun(10, func() { sp.Len() }); allocs != 0 {
+		t.Error("Len should not allocate, got", allocs)
+	}
+
+	msg.Records[0].SetME(false)
+	if l := sp.Len(); l != len(sp.Marshal()) || l != 0 {
+		t.Error("Len should match Marshal for invalid messages:", l)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// Message truncated in the middle of the URI
+	bts := []byte{0xD1, 0x01, 0x0E, 'U', 0x01, 'n', 'f', 'c'}
+	sp := new(SmartPosterPayload)
+	err := sp.Decode(bts)
+	if !errors.Is(err, ErrTruncated) {
+		t.Error("expected ErrTruncated, got", err)
+	}
 }
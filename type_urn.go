@@ -0,0 +1,114 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"strings"
+)
+
+// Prefixes of the type URNs of each TNF. The ones of NFC Forum Well-Known
+// and External types are defined in the NFC Record Type Definition
+// specification. The others are not standard, but make every record type
+// tell its TNF apart unambiguously.
+const (
+	WellKnownTypeURNPrefix   = "urn:nfc:wkt:"
+	MediaTypeURNPrefix       = "mime:"
+	AbsoluteURITypeURNPrefix = "uri:"
+	ExternalTypeURNPrefix    = "urn:nfc:ext:"
+)
+
+// typeURNPrefixes holds the prefix of the type URNs of each TNF. TNFs
+// without a type are identified by the prefix alone.
+var typeURNPrefixes = [...]string{
+	Empty:                 "",
+	NFCForumWellKnownType: WellKnownTypeURNPrefix,
+	MediaType:             MediaTypeURNPrefix,
+	AbsoluteURI:           AbsoluteURITypeURNPrefix,
+	NFCForumExternalType:  ExternalTypeURNPrefix,
+	Unknown:               "unknown",
+	Unchanged:             "unchanged",
+	Reserved:              "reserved",
+}
+
+// ErrBadTypeURN is returned by ParseTypeURN when the string does not
+// identify a record type.
+var ErrBadTypeURN = errors.New("NDEF: not a valid record type URN")
+
+// hasType returns true for the TNFs whose records have a type.
+func hasType(tnf TNF) bool {
+	switch tnf {
+	case NFCForumWellKnownType, MediaType, AbsoluteURI, NFCForumExternalType:
+		return true
+	default:
+		return false
+	}
+}
+
+// FormatTypeURN returns a single string identifying a record type:
+//
+//   - "urn:nfc:wkt:<type>" for NFC Forum Well-Known types
+//   - "mime:<type>" for Media types, i.e. "mime:text/plain"
+//   - "uri:<type>" for Absolute URI types
+//   - "urn:nfc:ext:<type>" for NFC Forum External types
+//   - "", "unknown", "unchanged" and "reserved" for the Empty, Unknown,
+//     Unchanged and Reserved TNFs, which have no type
+//
+// ParseTypeURN does the reverse. Invalid TNFs return an empty string.
+func FormatTypeURN(tnf TNF, typ string) string {
+	if int(tnf) >= len(typeURNPrefixes) {
+		return ""
+	}
+	if !hasType(tnf) {
+		return typeURNPrefixes[tnf]
+	}
+	return typeURNPrefixes[tnf] + typ
+}
+
+// ParseTypeURN returns the TNF and type name of a record type given as
+// FormatTypeURN returns it. The prefixes are case-insensitive, while type
+// names are returned as they are. Types cannot be empty.
+func ParseTypeURN(urn string) (TNF, string, error) {
+	for i, prefix := range typeURNPrefixes {
+		tnf := TNF(i)
+		switch {
+		case !hasType(tnf):
+			if strings.EqualFold(urn, prefix) {
+				return tnf, "", nil
+			}
+		case hasPrefixFold(urn, prefix):
+			typ := urn[len(prefix):]
+			if typ == "" {
+				return 0, "", ErrBadTypeURN
+			}
+			return tnf, typ, nil
+		}
+	}
+	return 0, "", ErrBadTypeURN
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// TypeURN returns a string identifying the TNF and type of the record.
+// See FormatTypeURN. It can differ from the Type() of the payload of the
+// record, which is not meant to identify the TNF.
+func (r *Record) TypeURN() string {
+	return FormatTypeURN(r.TNF(), r.Type())
+}
@@ -0,0 +1,87 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"testing"
+)
+
+func TestTypeURN(t *testing.T) {
+	tcs := []struct {
+		r   *Record
+		urn string
+	}{
+		{NewTextRecord("a", "en"), "urn:nfc:wkt:T"},
+		{NewExternalRecord("example.com:foo", nil), "urn:nfc:ext:example.com:foo"},
+		{NewMediaRecord("text/plain; charset=utf-8", nil), "mime:text/plain; charset=utf-8"},
+		{NewAbsoluteURIRecord("https://example.com/t", nil), "uri:https://example.com/t"},
+		{NewRecord(Empty, "", "", nil), ""},
+		{NewRecord(Unknown, "", "", nil), "unknown"},
+	}
+	for _, tc := range tcs {
+		if urn := tc.r.TypeURN(); urn != tc.urn {
+			t.Errorf("expected %q but got %q", tc.urn, urn)
+		}
+		tnf, typ, err := ParseTypeURN(tc.urn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tnf != tc.r.TNF() || typ != tc.r.Type() {
+			t.Errorf("%q: unexpected TNF %s and type %q", tc.urn, tnf, typ)
+		}
+	}
+
+	if _, typ, _ := ParseTypeURN("URN:NFC:WKT:Sp"); typ != "Sp" {
+		t.Error("prefixes should be case-insensitive")
+	}
+	if tnf, typ, _ := ParseTypeURN("uri:urn:nfc:sn:x"); tnf != AbsoluteURI || typ != "urn:nfc:sn:x" {
+		t.Error("URNs can be absolute URI types")
+	}
+	for _, bad := range []string{
+		"urn:nfc:wkt:", "urn:nfc:ext:", "mime:", "uri:",
+		"foo", "example.com:foo", "text/plain", "https://example.com/t",
+		"unknown:a", "Reserved ",
+	} {
+		if _, _, err := ParseTypeURN(bad); err != ErrBadTypeURN {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestTypeURNRoundTrip(t *testing.T) {
+	types := map[TNF]string{
+		NFCForumWellKnownType: "Sp",
+		MediaType:             "image/png",
+		AbsoluteURI:           "urn:example:t",
+		NFCForumExternalType:  "example.com:foo",
+	}
+	for tnf := Empty; tnf <= Reserved; tnf++ {
+		urn := FormatTypeURN(tnf, types[tnf])
+		gotTNF, gotType, err := ParseTypeURN(urn)
+		if err != nil {
+			t.Errorf("%s: %s", tnf, err)
+			continue
+		}
+		if gotTNF != tnf || gotType != types[tnf] {
+			t.Errorf("%s: %q was parsed as %s %q", tnf, urn, gotTNF, gotType)
+		}
+	}
+	if FormatTypeURN(TNF(8), "a") != "" {
+		t.Error("invalid TNFs should have no URN")
+	}
+}
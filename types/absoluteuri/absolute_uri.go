@@ -17,16 +17,150 @@
 
 // Package absoluteuri provides an implementation for NDEF Payloads
 // of Absolute URI type.
+//
+// The type of these payloads is an absolute URI (RFC 3986 4.3), as in
+// "https://example.com/type". Types are compared after normalizing them
+// (see NormalizeType).
 package absoluteuri
 
+import (
+	"errors"
+	"strings"
+)
+
 // Payload is a wrapper to store a Payload
 type Payload struct {
 	URIType string
 	Payload []byte
 }
 
+// Errors returned by CheckType.
+var (
+	ErrBadCharacter = errors.New("absoluteuri: type contains characters not allowed in URIs")
+	ErrNotAbsolute  = errors.New("absoluteuri: type is not an absolute URI")
+	ErrBadEscaping  = errors.New("absoluteuri: invalid escaping in the type")
+	ErrFragment     = errors.New("absoluteuri: type cannot have a fragment")
+)
+
+// CheckType verifies that uriType is an absolute URI as defined in RFC
+// 3986 4.3: it starts with a scheme, is made of the characters allowed in
+// URIs and well-formed %-escapes, and has no fragment. It does not
+// allocate.
+func CheckType(uriType string) error {
+	colon := -1
+	for i := 0; i < len(uriType); i++ {
+		c := uriType[i]
+		switch {
+		case c == '#':
+			return ErrFragment
+		case c == '%':
+			if i+2 >= len(uriType) || !isHex(uriType[i+1]) || !isHex(uriType[i+2]) {
+				return ErrBadEscaping
+			}
+			i += 2
+		case c == ':' && colon < 0:
+			colon = i
+		case !isUnreserved(c) && strings.IndexByte(":/?[]@!$&'()*+,;=", c) < 0:
+			return ErrBadCharacter
+		}
+	}
+	if colon <= 0 || !isScheme(uriType[:colon]) {
+		return ErrNotAbsolute
+	}
+	return nil
+}
+
+// isScheme returns true when s is ALPHA *( ALPHA / DIGIT / "+" / "-" / "." ).
+func isScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case i > 0 && ((c >= '0' && c <= '9') || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeType returns the normalized form of an URI type, as described
+// in RFC 3986 6.2.2: the scheme and the host are lowercased, the hex
+// digits of percent-encoded bytes are uppercased and percent-encoded
+// unreserved characters are decoded. Types which are not absolute URIs
+// are returned unchanged.
+func NormalizeType(uriType string) string {
+	if CheckType(uriType) != nil {
+		return uriType
+	}
+
+	i := strings.IndexByte(uriType, ':')
+	scheme, rest := strings.ToLower(uriType[:i]), uriType[i+1:]
+	if strings.HasPrefix(rest, "//") {
+		end := strings.IndexAny(rest[2:], "/?")
+		if end < 0 {
+			end = len(rest) - 2
+		}
+		authority := rest[2 : 2+end]
+		host := strings.LastIndexByte(authority, '@') + 1
+		authority = authority[:host] + strings.ToLower(authority[host:])
+		rest = "//" + authority + rest[2+end:]
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme)
+	b.WriteByte(':')
+	for i := 0; i < len(rest); i++ {
+		if rest[i] != '%' {
+			b.WriteByte(rest[i])
+			continue
+		}
+		// CheckType made sure escapes are complete.
+		c := unhex(rest[i+1])<<4 | unhex(rest[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString(strings.ToUpper(rest[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') ||
+		(c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+func isUnreserved(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9') || strings.IndexByte("-._~", c) >= 0
+}
+
+// EqualType returns true when both URI types are the same once
+// normalized. See NormalizeType.
+func EqualType(a, b string) bool {
+	return NormalizeType(a) == NormalizeType(b)
+}
+
+// Check verifies the type of the Payload. See CheckType.
+func (absu *Payload) Check() error {
+	return CheckType(absu.URIType)
+}
+
 // New returns a pointer to a Payload type holding the given payload with the
-// given type.
+// given type, which is used as is.
 func New(uriType string, payload []byte) *Payload {
 	return &Payload{
 		URIType: uriType,
@@ -34,6 +168,16 @@ func New(uriType string, payload []byte) *Payload {
 	}
 }
 
+// NewChecked returns a pointer to a Payload type holding the given
+// payload with the given type, or an error if the type is not an
+// absolute URI. See CheckType.
+func NewChecked(uriType string, payload []byte) (*Payload, error) {
+	if err := CheckType(uriType); err != nil {
+		return nil, err
+	}
+	return New(uriType, payload), nil
+}
+
 // String returns a string explaining that we are not sure how to print
 // this type.
 func (absu *Payload) String() string {
@@ -43,7 +187,8 @@ func (absu *Payload) String() string {
 	return ""
 }
 
-// Type returns the URI defining the type for this payload
+// Type returns the URI defining the type for this payload, without the
+// "uri:" prefix of its type URN.
 func (absu *Payload) Type() string {
 	return absu.URIType
 }
@@ -58,8 +203,15 @@ func (absu *Payload) Unmarshal(buf []byte) {
 	absu.Payload = buf
 }
 
+// Decode parses the payload. Since any content is valid, it never
+// returns an error.
+func (absu *Payload) Decode(buf []byte) error {
+	absu.Unmarshal(buf)
+	return nil
+}
+
 // Len is the length of the byte slice resulting of Marshaling
 // this Payload.
 func (absu *Payload) Len() int {
-	return len(absu.Marshal())
+	return len(absu.Payload)
 }
@@ -19,6 +19,7 @@ package absoluteuri
 
 import (
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -67,3 +68,91 @@ func TestLen(t *testing.T) {
 		t.Error("Unexpected length")
 	}
 }
+
+func TestDecode(t *testing.T) {
+	pl := new(Payload)
+	if err := pl.Decode([]byte{0x79}); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(pl.Payload, []byte{0x79}) {
+		t.Error("Bad decoding")
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	good := []string{
+		"http://a.b",
+		"urn:example:a",
+		"https://example.com/a%20b?x=1",
+		"mailto:a@example.com",
+	}
+	for _, typ := range good {
+		if err := CheckType(typ); err != nil {
+			t.Errorf("%s: %s", typ, err)
+		}
+	}
+
+	bad := []struct {
+		typ string
+		err error
+	}{
+		{"/relative/path", ErrNotAbsolute},
+		{"example.com", ErrNotAbsolute},
+		{"a/bc", ErrNotAbsolute},
+		{"1a:b", ErrNotAbsolute},
+		{"http://a.b/{x}", ErrBadCharacter},
+		{"http://a.b/ñ", ErrBadCharacter},
+		{"http://a.b/a b", ErrBadCharacter},
+		{"http://a.b/?%zz", ErrBadEscaping},
+		{"http://a.b/%2", ErrBadEscaping},
+		{"http://a.b/#x", ErrFragment},
+	}
+	for _, tc := range bad {
+		if err := CheckType(tc.typ); !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %s, got %v", tc.typ, tc.err, err)
+		}
+	}
+	if CheckType("") == nil {
+		t.Error("empty types should not be valid")
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		CheckType("https://example.com/a%20b?x=1")
+	})
+	if allocs > 0 {
+		t.Errorf("CheckType should not allocate, got %f allocations", allocs)
+	}
+
+	if _, err := NewChecked("a.b", nil); err == nil {
+		t.Error("expected an error")
+	}
+	if _, err := NewChecked("http://a.b", nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	tcs := []struct {
+		typ      string
+		expected string
+	}{
+		{"HTTP://Example.COM/Path", "http://example.com/Path"},
+		{"http://User@Example.com:80/", "http://User@example.com:80/"},
+		{"http://a.b/%7euser/%2f%41", "http://a.b/~user/%2FA"},
+		{"http://a.b?Q=%3d", "http://a.b?Q=%3D"},
+		{"URN:Example:A", "urn:Example:A"},
+		{"not absolute", "not absolute"},
+	}
+	for _, tc := range tcs {
+		if got := NormalizeType(tc.typ); got != tc.expected {
+			t.Errorf("%s: expected %s, got %s", tc.typ, tc.expected, got)
+		}
+	}
+
+	if !EqualType("HTTPS://EXAMPLE.com/%7Ea", "https://example.com/~a") {
+		t.Error("types should be equal")
+	}
+	if EqualType("https://example.com/A", "https://example.com/a") {
+		t.Error("paths are case-sensitive")
+	}
+}
@@ -17,14 +17,107 @@
 
 // Package ext provides an implementation for NDEF Payloads of NFC Forum
 // External Type.
+//
+// External type names have the form "<domain>:<name>", where the domain
+// is the domain name of the organization defining the type, as in
+// "example.com:mytype". They are compared case-insensitively.
 package ext
 
+import (
+	"errors"
+	"strings"
+)
+
 // Payload is a wrapper to store a Payload
 type Payload struct {
 	ExtType string
 	Payload []byte
 }
 
+// Errors returned by CheckType.
+var (
+	ErrNoDomain    = errors.New("ext: type name has no domain")
+	ErrNoName      = errors.New("ext: type name has no name after the domain")
+	ErrBadDomain   = errors.New("ext: invalid character in the domain of the type name")
+	ErrBadName     = errors.New("ext: invalid character in the type name")
+	ErrBadEscaping = errors.New("ext: invalid escaping in the type name")
+)
+
+// CheckType verifies that an external type name has the form
+// "<domain>:<name>". The domain can contain letters, digits, "-" and ".",
+// while the name can contain letters, digits, the characters in
+// "()+,-.:=@;$_!*'" and %-escaped bytes, as in URNs (RFC 2141).
+func CheckType(extType string) error {
+	i := strings.IndexByte(extType, ':')
+	if i <= 0 {
+		return ErrNoDomain
+	}
+	domain, name := extType[:i], extType[i+1:]
+	if name == "" {
+		return ErrNoName
+	}
+	for _, c := range []byte(domain) {
+		if !isAlphaNum(c) && c != '-' && c != '.' {
+			return ErrBadDomain
+		}
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case isAlphaNum(c) || strings.IndexByte("()+,-.:=@;$_!*'", c) >= 0:
+		case c == '%':
+			if i+2 >= len(name) || !isHex(name[i+1]) || !isHex(name[i+2]) {
+				return ErrBadEscaping
+			}
+			i += 2
+		default:
+			return ErrBadName
+		}
+	}
+	return nil
+}
+
+func isAlphaNum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') ||
+		(c >= 'A' && c <= 'F')
+}
+
+// EqualType returns true when both external type names are the same,
+// which is compared case-insensitively.
+func EqualType(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// Domain returns the domain part of the type name, before the first
+// ":", or an empty string if there is none.
+func (extT *Payload) Domain() string {
+	i := strings.IndexByte(extT.ExtType, ':')
+	if i < 0 {
+		return ""
+	}
+	return extT.ExtType[:i]
+}
+
+// Name returns the part of the type name after the domain, or an empty
+// string if there is none.
+func (extT *Payload) Name() string {
+	i := strings.IndexByte(extT.ExtType, ':')
+	if i < 0 {
+		return ""
+	}
+	return extT.ExtType[i+1:]
+}
+
+// Check verifies the type name of the Payload. See CheckType.
+func (extT *Payload) Check() error {
+	return CheckType(extT.ExtType)
+}
+
 // New returns a pointer to a Payload type holding the given payload with the
 // given type.
 func New(extType string, payload []byte) *Payload {
@@ -34,6 +127,15 @@ func New(extType string, payload []byte) *Payload {
 	}
 }
 
+// NewChecked is like New, but returns an error if the type is not a
+// valid external type name. See CheckType.
+func NewChecked(extType string, payload []byte) (*Payload, error) {
+	if err := CheckType(extType); err != nil {
+		return nil, err
+	}
+	return New(extType, payload), nil
+}
+
 // String returns a string explaining that we are not sure how to print
 // this type.
 func (extT *Payload) String() string {
@@ -58,8 +160,15 @@ func (extT *Payload) Unmarshal(buf []byte) {
 	extT.Payload = buf
 }
 
+// Decode parses the payload. Since any content is valid, it never
+// returns an error.
+func (extT *Payload) Decode(buf []byte) error {
+	extT.Unmarshal(buf)
+	return nil
+}
+
 // Len is the length of the byte slice resulting of Marshaling
 // this Payload.
 func (extT *Payload) Len() int {
-	return len(extT.Marshal())
+	return len(extT.Payload)
 }
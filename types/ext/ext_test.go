@@ -67,3 +67,52 @@ func TestLen(t *testing.T) {
 		t.Error("Unexpected length")
 	}
 }
+
+func TestDecode(t *testing.T) {
+	pl := new(Payload)
+	if err := pl.Decode([]byte{0x79}); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(pl.Payload, []byte{0x79}) {
+		t.Error("Bad decoding")
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	good := []string{"example.com:foo", "Example.COM:a:b", "a-b.c:x(1)%2F_*'"}
+	for _, typ := range good {
+		if err := CheckType(typ); err != nil {
+			t.Errorf("%q: %s", typ, err)
+		}
+	}
+
+	bad := map[string]error{
+		"foo":             ErrNoDomain,
+		":foo":            ErrNoDomain,
+		"example.com:":    ErrNoName,
+		"exa_mple.com:a":  ErrBadDomain,
+		"example.com:a b": ErrBadName,
+		"example.com:a#":  ErrBadName,
+		"example.com:a%2": ErrBadEscaping,
+		"example.com:%zz": ErrBadEscaping,
+	}
+	for typ, expected := range bad {
+		if err := CheckType(typ); err != expected {
+			t.Errorf("%q: expected %s, got %v", typ, expected, err)
+		}
+	}
+
+	if _, err := NewChecked("foo", nil); err != ErrNoDomain {
+		t.Error("NewChecked should check the type")
+	}
+	ext, err := NewChecked("example.com:foo:bar", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ext.Domain() != "example.com" || ext.Name() != "foo:bar" {
+		t.Error("unexpected domain and name")
+	}
+	if !EqualType("Example.com:Foo", "example.COM:foo") {
+		t.Error("types should be compared case-insensitively")
+	}
+}
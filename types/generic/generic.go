@@ -56,7 +56,14 @@ func (g *Payload) Unmarshal(buf []byte) {
 	g.Payload = buf
 }
 
+// Decode parses the payload. Since any content is valid, it never
+// returns an error.
+func (g *Payload) Decode(buf []byte) error {
+	g.Unmarshal(buf)
+	return nil
+}
+
 // Len is the length of the byte slice resulting of Marshaling.
 func (g *Payload) Len() int {
-	return len(g.Marshal())
+	return len(g.Payload)
 }
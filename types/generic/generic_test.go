@@ -67,3 +67,13 @@ func TestLen(t *testing.T) {
 		t.Error("Unexpected length")
 	}
 }
+
+func TestDecode(t *testing.T) {
+	pl := new(Payload)
+	if err := pl.Decode([]byte{0x79}); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(pl.Payload, []byte{0x79}) {
+		t.Error("Bad decoding")
+	}
+}
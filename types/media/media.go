@@ -17,16 +17,176 @@
 
 // Package media provides an implementation for NDEF Payloads for media
 // types.
+//
+// Media types follow RFC 2046, as in "text/plain; charset=utf-8". The
+// type, subtype and parameter names are compared case-insensitively, as
+// well as the values of the charset parameter.
 package media
 
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"mime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
 // Payload is a wrapper to store a Payload
 type Payload struct {
 	MimeType string
 	Payload  []byte
 }
 
+// Errors returned by CheckType and ParseType.
+var (
+	ErrBadType      = errors.New("media: invalid character in the media type")
+	ErrNoSubtype    = errors.New("media: media type has no subtype")
+	ErrBadParameter = errors.New("media: invalid media type parameter")
+)
+
+// ParseType parses a media type with its parameters, as
+// mime.ParseMediaType does, but requiring a "type/subtype" form. The
+// media type is returned in lowercase.
+func ParseType(mimeType string) (string, map[string]string, error) {
+	mediaType, params, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return "", nil, err
+	}
+	if i := strings.IndexByte(mediaType, '/'); i <= 0 || i == len(mediaType)-1 {
+		return "", nil, ErrNoSubtype
+	}
+	return mediaType, params, nil
+}
+
+// CheckType verifies the syntax of a media type (RFC 2045 5.1): a type
+// and a subtype separated by "/", optionally followed by parameters as in
+// "; charset=utf-8", whose values are tokens or quoted strings. Unlike
+// ParseType, it does not allocate, so it can be used on every record.
+func CheckType(mimeType string) error {
+	s := skipSpace(mimeType)
+	n := tokenLen(s)
+	if n == 0 {
+		return ErrBadType
+	}
+	s = s[n:]
+	if s == "" || s[0] != '/' {
+		return ErrNoSubtype
+	}
+	s = s[1:]
+	if n = tokenLen(s); n == 0 {
+		return ErrNoSubtype
+	}
+	s = skipSpace(s[n:])
+
+	for s != "" {
+		if s[0] != ';' {
+			return ErrBadType
+		}
+		s = skipSpace(s[1:])
+		if s == "" {
+			// a trailing ";" is tolerated, as in mime.ParseMediaType
+			break
+		}
+		if n = tokenLen(s); n == 0 {
+			return ErrBadParameter
+		}
+		s = skipSpace(s[n:])
+		if s == "" || s[0] != '=' {
+			return ErrBadParameter
+		}
+		s = skipSpace(s[1:])
+		if n = valueLen(s); n == 0 {
+			return ErrBadParameter
+		}
+		s = skipSpace(s[n:])
+	}
+	return nil
+}
+
+func skipSpace(s string) string {
+	for len(s) > 0 && (s[0] == ' ' || s[0] == '\t') {
+		s = s[1:]
+	}
+	return s
+}
+
+// tokenLen returns the length of the RFC 2045 token at the start of s.
+func tokenLen(s string) int {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`()<>@,;:\"/[]?=`, c) >= 0 {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// valueLen returns the length of the parameter value, a token or a
+// quoted string, at the start of s, or 0 if there is none.
+func valueLen(s string) int {
+	if s == "" || s[0] != '"' {
+		return tokenLen(s)
+	}
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"':
+			return i + 1
+		case c == '\\':
+			i++
+		case (c < ' ' && c != '\t') || c >= 0x7f:
+			return 0
+		}
+	}
+	return 0
+}
+
+// NormalizeType returns the canonical form of a media type: the type,
+// the subtype, the parameter names and the charset are lowercased, and
+// parameters are sorted and separated by "; ". Other parameter values
+// are kept. Media types
+// which cannot be parsed are lowercased, as they are compared
+// case-insensitively (see EqualType).
+func NormalizeType(mimeType string) string {
+	mediaType, params, err := ParseType(mimeType)
+	if err != nil {
+		return strings.ToLower(mimeType)
+	}
+	if charset, ok := params["charset"]; ok {
+		params["charset"] = strings.ToLower(charset)
+	}
+	if normalized := mime.FormatMediaType(mediaType, params); normalized != "" {
+		return normalized
+	}
+	return strings.ToLower(mimeType)
+}
+
+// EqualType returns true when both media types are the same, with the
+// same parameters. Charsets are compared case-insensitively, while other
+// parameter values are compared exactly. Media types which cannot be
+// parsed are compared case-insensitively.
+func EqualType(a, b string) bool {
+	aType, aParams, aErr := ParseType(a)
+	bType, bParams, bErr := ParseType(b)
+	if aErr != nil || bErr != nil {
+		return strings.EqualFold(a, b)
+	}
+	if aType != bType || len(aParams) != len(bParams) {
+		return false
+	}
+	for k, v := range aParams {
+		bv, ok := bParams[k]
+		if !ok || (bv != v && !(k == "charset" && strings.EqualFold(bv, v))) {
+			return false
+		}
+	}
+	return true
+}
+
 // New returns a pointer to a Payload type holding the given payload with the
-// given type.
+// given type. The media type is kept as given, even if it is not valid.
 func New(mimeType string, payload []byte) *Payload {
 	return &Payload{
 		MimeType: mimeType,
@@ -34,16 +194,99 @@ func New(mimeType string, payload []byte) *Payload {
 	}
 }
 
-// String returns a string explaining that we are not sure how to print
-// this type.
+// NewChecked is like New, but fails when mimeType is not a valid media
+// type. See CheckType.
+func NewChecked(mimeType string, payload []byte) (*Payload, error) {
+	if err := CheckType(mimeType); err != nil {
+		return nil, err
+	}
+	return New(mimeType, payload), nil
+}
+
+// MediaType returns the media type in lowercase, without parameters, and
+// the parameters. See ParseType.
+func (media *Payload) MediaType() (string, map[string]string, error) {
+	return ParseType(media.MimeType)
+}
+
+// Check verifies the media type of the Payload. See CheckType.
+func (media *Payload) Check() error {
+	return CheckType(media.MimeType)
+}
+
+// String returns the contents of "text/*" payloads, decoded with the
+// declared charset, and the indented contents of JSON payloads. For
+// other payloads, or when decoding fails, it returns a string explaining
+// that we are not sure how to print this type.
 func (media *Payload) String() string {
-	if media.Len() > 0 {
-		return "<The message contains a payload>"
+	if media.Len() == 0 {
+		return ""
+	}
+	if str, ok := media.text(); ok {
+		return str
+	}
+	return "<The message contains a payload>"
+}
+
+// text returns the payload as text, if it is printable.
+func (media *Payload) text() (string, bool) {
+	mediaType, params, err := media.MediaType()
+	if err != nil {
+		return "", false
+	}
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, media.Payload, "", "  "); err != nil {
+			return "", false
+		}
+		return buf.String(), true
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return decodeText(media.Payload, params["charset"])
+	}
+	return "", false
+}
+
+// decodeText decodes text in the given charset. UTF-8 is assumed when
+// no charset is given.
+func decodeText(text []byte, charset string) (string, bool) {
+	switch strings.ToLower(charset) {
+	case "", "utf-8", "utf8", "us-ascii":
+		if !utf8.Valid(text) {
+			return "", false
+		}
+		return string(text), true
+	case "iso-8859-1", "latin1":
+		runes := make([]rune, len(text))
+		for i, b := range text {
+			runes[i] = rune(b)
+		}
+		return string(runes), true
+	case "utf-16", "utf-16be", "utf-16le":
+		if len(text)%2 != 0 {
+			return "", false
+		}
+		var order binary.ByteOrder = binary.BigEndian
+		if strings.EqualFold(charset, "utf-16le") {
+			order = binary.LittleEndian
+		} else if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+			order = binary.LittleEndian
+			text = text[2:]
+		} else if len(text) >= 2 && text[0] == 0xFE && text[1] == 0xFF {
+			text = text[2:]
+		}
+		units := make([]uint16, len(text)/2)
+		for i := range units {
+			units[i] = order.Uint16(text[2*i:])
+		}
+		return string(utf16.Decode(units)), true
+	default:
+		return "", false
 	}
-	return ""
 }
 
-// Type returns the mime type of this payload.
+// Type returns the mime type of this payload, without the "mime:" prefix
+// of its type URN.
 func (media *Payload) Type() string {
 	return media.MimeType
 }
@@ -58,8 +301,15 @@ func (media *Payload) Unmarshal(buf []byte) {
 	media.Payload = buf
 }
 
+// Decode parses the payload. Since any content is valid, it never
+// returns an error.
+func (media *Payload) Decode(buf []byte) error {
+	media.Unmarshal(buf)
+	return nil
+}
+
 // Len is the length of the byte slice resulting of Marshaling
 // this Payload.
 func (media *Payload) Len() int {
-	return len(media.Marshal())
+	return len(media.Payload)
 }
@@ -42,6 +42,106 @@ func TestString(t *testing.T) {
 	if media.String() != "" {
 		t.Error("Expected an empty string")
 	}
+
+	tcs := []struct {
+		mimeType string
+		payload  []byte
+		expected string
+	}{
+		{"text/plain", []byte("héllo"), "héllo"},
+		{"Text/Plain; charset=ISO-8859-1", []byte{'h', 0xe9}, "hé"},
+		{"text/plain; charset=utf-16", []byte{0xff, 0xfe, 'h', 0, 'i', 0}, "hi"},
+		{"text/plain; charset=utf-16be", []byte{0, 'h', 0, 'i'}, "hi"},
+		{"text/plain", []byte{0xff}, "<The message contains a payload>"},
+		{"text/plain; charset=koi8-r", []byte("a"), "<The message contains a payload>"},
+		{"application/json", []byte(`{"a":1}`), "{\n  \"a\": 1\n}"},
+		{"application/ld+json", []byte(`[1]`), "[\n  1\n]"},
+		{"application/json", []byte(`{`), "<The message contains a payload>"},
+	}
+	for _, tc := range tcs {
+		got := New(tc.mimeType, tc.payload).String()
+		if got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.mimeType, tc.expected, got)
+		}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	typ, params, err := ParseType("Text/Plain; Charset=UTF-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if typ != "text/plain" || params["charset"] != "UTF-8" {
+		t.Error("unexpected media type:", typ, params)
+	}
+
+	for _, bad := range []string{"", "text", "text/", "/plain", "a b/c"} {
+		if CheckType(bad) == nil {
+			t.Errorf("%q should not be valid", bad)
+		}
+	}
+	if _, err := NewChecked("text", nil); err == nil {
+		t.Error("expected an error")
+	}
+	if _, err := NewChecked("a/b", nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	good := []string{
+		"a/b",
+		"text/plain; charset=utf-8",
+		"Text/Plain;Charset=UTF-8",
+		"application/vnd.example+json; a=1; b=\"x; \\\"y\"",
+		"text/plain;",
+	}
+	for _, typ := range good {
+		if err := CheckType(typ); err != nil {
+			t.Errorf("%q: %s", typ, err)
+		}
+	}
+
+	bad := map[string]error{
+		"":                      ErrBadType,
+		"vcrd":                  ErrNoSubtype,
+		"a/b/c":                 ErrBadType,
+		"a/b c":                 ErrBadType,
+		"a/b; charset":          ErrBadParameter,
+		"a/b; charset=":         ErrBadParameter,
+		"a/b; a=\"unterminated": ErrBadParameter,
+		"a/b; a=é":              ErrBadParameter,
+	}
+	for typ, expected := range bad {
+		if err := CheckType(typ); err != expected {
+			t.Errorf("%q: expected %s, got %v", typ, expected, err)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		CheckType("text/plain; charset=\"utf-8\"")
+	})
+	if allocs > 0 {
+		t.Errorf("CheckType should not allocate, got %f allocations", allocs)
+	}
+}
+
+func TestEqualType(t *testing.T) {
+	if !EqualType("text/plain; charset=utf-8", "TEXT/Plain;Charset=utf-8") {
+		t.Error("types should be equal")
+	}
+	if !EqualType("text/plain; charset=UTF-8", "text/plain; charset=utf-8") {
+		t.Error("charsets should be compared case-insensitively")
+	}
+	if EqualType("a/b; x=UP", "a/b; x=up") {
+		t.Error("other parameter values should be compared exactly")
+	}
+	if EqualType("text/plain", "text/plain; charset=utf-8") {
+		t.Error("parameters should be compared")
+	}
+	if EqualType("text/plain", "text/html") {
+		t.Error("types should be different")
+	}
 }
 
 func TestMarshal(t *testing.T) {
@@ -67,3 +167,26 @@ func TestLen(t *testing.T) {
 		t.Error("Unexpected length")
 	}
 }
+
+func TestDecode(t *testing.T) {
+	pl := new(Payload)
+	if err := pl.Decode([]byte{0x79}); err != nil {
+		t.Error(err)
+	}
+	if !bytes.Equal(pl.Payload, []byte{0x79}) {
+		t.Error("Bad decoding")
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	tcs := map[string]string{
+		"Text/Plain;Charset=UTF-8": "text/plain; charset=utf-8",
+		"a/b; y=1;X=\"2\"":         "a/b; x=2; y=1",
+		"Not A Type":               "not a type",
+	}
+	for typ, expected := range tcs {
+		if got := NormalizeType(typ); got != expected {
+			t.Errorf("%q: expected %q, got %q", typ, expected, got)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+// Package action provides support for the payload of the local "act"
+// records found in Smart Posters, following the NFC Forum Smart Poster
+// Record Type Definition (NFCForum-SmartPoster_RTD_1.0). They tell what
+// the reader should do with the content of the Smart Poster.
+//
+// The Payload type implements the RecordPayload interface from ndef,
+// so it can be used as ndef.Record.Payload.
+package action
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Recommended actions.
+const (
+	Do   byte = 0 // Do the action (open the URI, send the SMS...)
+	Save byte = 1 // Save the content for later
+	Open byte = 2 // Open the content for editing
+)
+
+var actionNames = map[byte]string{
+	Do:   "do",
+	Save: "save",
+	Open: "open",
+}
+
+// Payload represents a NDEF Record Payload of local type "act".
+type Payload struct {
+	Action byte
+}
+
+// New returns a pointer to a Payload object with the given action.
+func New(action byte) *Payload {
+	return &Payload{
+		Action: action,
+	}
+}
+
+// String returns the name of the action, i.e. "save". Values reserved
+// for future use are printed as numbers.
+func (a *Payload) String() string {
+	if name, ok := actionNames[a.Action]; ok {
+		return name
+	}
+	return fmt.Sprintf("reserved (%d)", a.Action)
+}
+
+// Type returns the Uniform Resource Name for actions.
+func (a *Payload) Type() string {
+	return "urn:nfc:wkt:act"
+}
+
+// Marshal returns the bytes representing the payload of an action record.
+func (a *Payload) Marshal() []byte {
+	return []byte{a.Action}
+}
+
+// ErrBadLength is returned by Decode when the payload is not a single
+// byte.
+var ErrBadLength = errors.New("action: payload must be one byte long")
+
+// Unmarshal parses the payload of an action record.
+func (a *Payload) Unmarshal(buf []byte) {
+	a.Decode(buf)
+}
+
+// Decode parses the payload of an action record, returning an error when
+// it is not a single byte. Actions reserved for future use are accepted.
+func (a *Payload) Decode(buf []byte) error {
+	a.Action = 0
+	if len(buf) != 1 {
+		return ErrBadLength
+	}
+	a.Action = buf[0]
+	return nil
+}
+
+// Len is the length of the byte slice resulting of Marshaling.
+func (a *Payload) Len() int {
+	return 1
+}
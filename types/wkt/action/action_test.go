@@ -0,0 +1,64 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package action
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	a := New(Save)
+	if a.Action != 1 {
+		t.Error("The payload should hold the given action")
+	}
+	if a.Type() != "urn:nfc:wkt:act" {
+		t.Error("Unexpected URN")
+	}
+}
+
+func TestString(t *testing.T) {
+	if New(Open).String() != "open" {
+		t.Error("Bad string generation")
+	}
+	if New(7).String() != "reserved (7)" {
+		t.Error("Bad string generation for reserved actions")
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	a := New(Open)
+	if !bytes.Equal(a.Marshal(), []byte{0x02}) || a.Len() != 1 {
+		t.Error("Bad payload generation")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	a := new(Payload)
+	if err := a.Decode([]byte{0x01}); err != nil {
+		t.Fatal(err)
+	}
+	if a.Action != Save {
+		t.Error("Bad decoding")
+	}
+	for _, bad := range [][]byte{nil, {0x00, 0x01}} {
+		if err := a.Decode(bad); err != ErrBadLength {
+			t.Errorf("%x: expected %s, got %v", bad, ErrBadLength, err)
+		}
+	}
+}
@@ -18,8 +18,6 @@
 // BUG(hector): The implementation ignores the guidelines about displaying the
 // text and removing the control characters.
 
-// BUG(hector): UTF-16 with different byte order, with/without BOM is not tested.
-
 // Package text provides support for NDEF Payloads of Text type.
 // It follows the NFC Forum Text Record Type Definition specification
 // (NFCForum-TS-RTD_Text_1.0).
@@ -30,8 +28,10 @@ package text
 
 import (
 	"bytes"
-	"strings"
+	"encoding/binary"
+	"errors"
 	"unicode/utf16"
+	"unicode/utf8"
 )
 
 // Payload represents a NDEF Record Payload of type "T", which
@@ -72,48 +72,78 @@ func (t *Payload) Marshal() []byte {
 	return buf.Bytes()
 }
 
+// Errors returned by Decode.
+var (
+	ErrEmpty          = errors.New("text: empty payload: missing status byte")
+	ErrReservedBit    = errors.New("text: reserved bit set in status byte")
+	ErrShortLanguage  = errors.New("text: payload shorter than language code length")
+	ErrOddUTF16Length = errors.New("text: odd number of bytes in UTF-16 text")
+	ErrInvalidUTF8    = errors.New("text: invalid UTF-8 text")
+)
+
 // Unmarshal parses the Payload from a text Record.
 func (t *Payload) Unmarshal(buf []byte) {
+	t.Decode(buf)
+}
+
+// Decode parses the Payload from a text Record, returning an error
+// when it is malformed. UTF-16 text is big-endian unless it carries a
+// byte order mark.
+func (t *Payload) Decode(buf []byte) error {
 	t.Language = ""
 	t.Text = ""
-	i := byte(0)
 	if len(buf) < 1 {
-		return
+		return ErrEmpty
 	}
-	firstByte := buf[i]
-	i++
+	firstByte := buf[0]
 	isUtf16 := firstByte>>7 == 1
-	// firstByte>>6 must be set to 0
-	ianaLen := 0x3F & firstByte // last 5 bytes
-	if len(buf) < int(i+ianaLen) {
-		return
+	ianaLen := int(0x3F & firstByte) // last 6 bits
+	if 1+ianaLen > len(buf) {
+		t.Language = string(buf[1:])
+		return ErrShortLanguage
 	}
-	t.Language = string(buf[i : i+ianaLen])
-	i += ianaLen
-	if len(buf) < int(i) {
-		return
+	t.Language = string(buf[1 : 1+ianaLen])
+	text := buf[1+ianaLen:]
+
+	var err error
+	if firstByte&0x40 != 0 {
+		// bit 6 is RFU and must be set to 0
+		err = ErrReservedBit
 	}
-	if isUtf16 {
-		//Convert buf to []uint16
-		bytesBuf := bytes.NewBuffer(buf[i:])
-		var finished bool
-		var uint16buf []uint16
-		for !finished {
-			b1, err := bytesBuf.ReadByte()
-			b2, err := bytesBuf.ReadByte()
-			uint16buf = append(uint16buf,
-				uint16(b1<<8)|uint16(b2))
-			finished = err != nil
+
+	if !isUtf16 {
+		t.Text = string(text)
+		if err == nil && !utf8.Valid(text) {
+			err = ErrInvalidUTF8
 		}
-		runes := utf16.Decode(uint16buf)
-		// It appears we get an extra trailing char at the end
-		t.Text = strings.TrimSuffix(string(runes), "\x00")
-	} else {
-		t.Text = string(buf[i:])
+		return err
+	}
+
+	if len(text)%2 != 0 {
+		if err == nil {
+			err = ErrOddUTF16Length
+		}
+		text = text[:len(text)-1]
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if len(text) >= 2 {
+		switch {
+		case text[0] == 0xFE && text[1] == 0xFF:
+			text = text[2:]
+		case text[0] == 0xFF && text[1] == 0xFE:
+			order = binary.LittleEndian
+			text = text[2:]
+		}
+	}
+	uint16buf := make([]uint16, len(text)/2)
+	for i := range uint16buf {
+		uint16buf[i] = order.Uint16(text[2*i:])
 	}
+	t.Text = string(utf16.Decode(uint16buf))
+	return err
 }
 
 // Len is the length of the byte slice resulting of Marshaling..
 func (t *Payload) Len() int {
-	return len(t.Marshal())
+	return 1 + len(t.Language) + len(t.Text)
 }
@@ -77,3 +77,35 @@ func TestLen(t *testing.T) {
 		t.Error("Unexpected length")
 	}
 }
+
+func TestDecode(t *testing.T) {
+	tcs := []struct {
+		name string
+		buf  []byte
+		err  error
+		lang string
+		text string
+	}{
+		{"ok", []byte{0x02, 'e', 'n', 'h', 'i'}, nil, "en", "hi"},
+		{"empty", []byte{}, ErrEmpty, "", ""},
+		{"short_language", []byte{0x05, 'e', 'n'}, ErrShortLanguage, "en", ""},
+		{"reserved_bit", []byte{0x42, 'e', 'n', 'h', 'i'}, ErrReservedBit, "en", "hi"},
+		{"bad_utf8", []byte{0x02, 'e', 'n', 0xff}, ErrInvalidUTF8, "en", "\xff"},
+		{"utf16_odd", []byte{0x82, 'e', 'n', 0x00, 'h', 0x00}, ErrOddUTF16Length, "en", "h"},
+		{"utf16_bom_be", []byte{0x82, 'e', 'n', 0xfe, 0xff, 0x00, 'h'}, nil, "en", "h"},
+		{"utf16_bom_le", []byte{0x82, 'e', 'n', 0xff, 0xfe, 'h', 0x00}, nil, "en", "h"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			tx := new(Payload)
+			err := tx.Decode(tc.buf)
+			if err != tc.err {
+				t.Errorf("expected error %v, got %v", tc.err, err)
+			}
+			if tx.Language != tc.lang || tx.Text != tc.text {
+				t.Errorf("unexpected payload: %q %q", tx.Language, tx.Text)
+			}
+		})
+	}
+}
@@ -24,8 +24,10 @@
 package uri
 
 import (
+	"errors"
 	"regexp"
 	"strings"
+	"unicode/utf8"
 )
 
 // URIProtocols provides a mapping between the first byte if a NDEF Payload of
@@ -109,19 +111,35 @@ func (u *Payload) Marshal() []byte {
 	return append(p, []byte(u.URIField)...)
 }
 
+// Errors returned by Decode.
+var (
+	ErrEmpty       = errors.New("uri: empty payload: missing identifier code")
+	ErrInvalidUTF8 = errors.New("uri: URI field is not valid UTF-8")
+)
+
 // Unmarshal parses the payload of a URI type record.
 func (u *Payload) Unmarshal(buf []byte) {
+	u.Decode(buf)
+}
+
+// Decode parses the payload of a URI type record, returning an error
+// when it is malformed. Identifier codes reserved for future use are
+// accepted and treated as having no prefix.
+func (u *Payload) Decode(buf []byte) error {
 	u.IdentCode = 0
 	u.URIField = ""
-	if len(buf) > 0 {
-		u.IdentCode = buf[0]
+	if len(buf) < 1 {
+		return ErrEmpty
 	}
-	if len(buf) > 1 {
-		u.URIField = string(buf[1:])
+	u.IdentCode = buf[0]
+	u.URIField = string(buf[1:])
+	if !utf8.Valid(buf[1:]) {
+		return ErrInvalidUTF8
 	}
+	return nil
 }
 
 // Len is the length of the byte slice resulting of Marshaling.
 func (u *Payload) Len() int {
-	return len(u.Marshal())
+	return 1 + len(u.URIField)
 }
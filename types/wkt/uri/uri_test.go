@@ -68,3 +68,19 @@ func TestLen(t *testing.T) {
 		t.Error("Unexpected length")
 	}
 }
+
+func TestDecode(t *testing.T) {
+	u := new(Payload)
+	if err := u.Decode([]byte{}); err != ErrEmpty {
+		t.Error("expected ErrEmpty, got", err)
+	}
+	if err := u.Decode([]byte{0x03, 0xff}); err != ErrInvalidUTF8 {
+		t.Error("expected ErrInvalidUTF8, got", err)
+	}
+	if err := u.Decode([]byte{0x03, 'a'}); err != nil {
+		t.Error(err)
+	}
+	if u.String() != "http://a" {
+		t.Error("Bad decoding")
+	}
+}
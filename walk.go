@@ -0,0 +1,151 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MessagePayload is implemented by RecordPayloads which hold an NDEF
+// Message, like SmartPosterPayload, so that Walk descends into them.
+type MessagePayload interface {
+	RecordPayload
+	// Returns the NDEF Message in the Payload.
+	NestedMessage() *Message
+}
+
+// NestedMessage returns the Message in the Smart Poster.
+func (sp *SmartPosterPayload) NestedMessage() *Message {
+	return sp.Message
+}
+
+// PathElement is a step in a Path: a record with the given index in a
+// message, and its type.
+type PathElement struct {
+	Index int
+	Type  string
+}
+
+// A Path locates a record in a Message. It lists the records containing
+// it, starting from the top-level record, and ends with the record itself.
+type Path []PathElement
+
+// String returns a readable representation of the Path, like
+// "record 0 > Sp > record 1".
+func (p Path) String() string {
+	elems := make([]string, 0, 2*len(p))
+	for i, e := range p {
+		elems = append(elems, fmt.Sprintf("record %d", e.Index))
+		if i < len(p)-1 {
+			elems = append(elems, e.Type)
+		}
+	}
+	return strings.Join(elems, " > ")
+}
+
+// SkipNested can be returned by a WalkFunc to avoid descending into the
+// message nested in the payload of the record. It is not returned as
+// an error by Walk.
+var SkipNested = errors.New("skip the nested message")
+
+// WalkFunc is called by Walk for every record. The path must not be
+// retained, as it is modified during the walk.
+//
+// When it returns an error, the walk stops and Walk returns it, unless
+// it is SkipNested.
+type WalkFunc func(path Path, r *Record) error
+
+// Walk calls fn for every record in the Message in order, descending
+// into the messages nested in their payloads (see MessagePayload) right
+// after calling it for the record containing them. Payloads which cannot
+// be decoded are not descended into.
+//
+// Records in nested messages are decoded from the payload of the record
+// containing them, so modifying them has no effect on it.
+func (m *Message) Walk(fn WalkFunc) error {
+	return m.walk(nil, fn)
+}
+
+func (m *Message) walk(path Path, fn WalkFunc) error {
+	for i, r := range m.Records {
+		p := append(path, PathElement{Index: i, Type: r.Type()})
+		err := fn(p, r)
+		if err == SkipNested {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if r.Empty() {
+			continue
+		}
+		pl, err := r.Payload()
+		if err != nil {
+			continue
+		}
+		if mp, ok := pl.(MessagePayload); ok && mp.NestedMessage() != nil {
+			if err := mp.NestedMessage().walk(p, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Filter returns the records for which fn returns true, including those
+// in nested messages, in the order of Walk.
+func (m *Message) Filter(fn func(r *Record) bool) []*Record {
+	var records []*Record
+	m.Walk(func(_ Path, r *Record) error {
+		if fn(r) {
+			records = append(records, r)
+		}
+		return nil
+	})
+	return records
+}
+
+// FindByType returns the records with the given TNF and type, including
+// those in nested messages, in the order of Walk. Types are compared as
+// in Record.Equal.
+func (m *Message) FindByType(tnf TNF, typ string) []*Record {
+	return m.Filter(func(r *Record) bool {
+		return r.TNF() == tnf && equalType(tnf, r.Type(), typ)
+	})
+}
+
+// FindByID returns the first record with the given ID in the order of
+// Walk, or nil if there is none. Records without ID are never found.
+func (m *Message) FindByID(id string) *Record {
+	var found *Record
+	if id == "" {
+		return nil
+	}
+	m.Walk(func(_ Path, r *Record) error {
+		if r.ID() == id {
+			found = r
+			return errFound
+		}
+		return nil
+	})
+	return found
+}
+
+var errFound = errors.New("found")
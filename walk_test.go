@@ -0,0 +1,105 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package ndef
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func walkTestMessage(t *testing.T) *Message {
+	title := NewTextRecord("title", "en")
+	if err := title.SetID("title"); err != nil {
+		t.Fatal(err)
+	}
+	return NewMessageFromRecords(
+		NewSmartPosterRecord(NewMessageFromRecords(
+			NewURIRecord("https://a.com"),
+			title,
+		)),
+		NewMediaRecord("Text/Plain", []byte("hi")),
+		NewURIRecord("https://b.com"),
+	)
+}
+
+func TestWalk(t *testing.T) {
+	m := walkTestMessage(t)
+	var visited []string
+	err := m.Walk(func(p Path, r *Record) error {
+		visited = append(visited, p.String()+": "+r.Type())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"record 0: Sp",
+		"record 0 > Sp > record 0: U",
+		"record 0 > Sp > record 1: T",
+		"record 1: Text/Plain",
+		"record 2: U",
+	}
+	if strings.Join(visited, "\n") != strings.Join(expected, "\n") {
+		t.Error("unexpected walk:\n" + strings.Join(visited, "\n"))
+	}
+
+	visited = nil
+	m.Walk(func(p Path, r *Record) error {
+		visited = append(visited, p.String())
+		return SkipNested
+	})
+	if len(visited) != 3 {
+		t.Error("nested messages should have been skipped:", visited)
+	}
+
+	stop := errors.New("stop")
+	n := 0
+	err = m.Walk(func(p Path, r *Record) error {
+		n++
+		return stop
+	})
+	if err != stop || n != 1 {
+		t.Error("the walk should stop with the error")
+	}
+}
+
+func TestFind(t *testing.T) {
+	m := walkTestMessage(t)
+
+	uris := m.FindByType(NFCForumWellKnownType, "U")
+	if len(uris) != 2 || uris[0].String() != "urn:nfc:wkt:U:https://a.com" {
+		t.Error("unexpected URI records:", uris)
+	}
+	if media := m.FindByType(MediaType, "text/plain"); len(media) != 1 {
+		t.Error("media types should match case-insensitively")
+	}
+	if r := m.FindByID("title"); r == nil || r.Type() != "T" {
+		t.Error("expected to find the title")
+	}
+	if r := m.FindByID(""); r != nil {
+		t.Error("records without ID should not be found")
+	}
+
+	short := m.Filter(func(r *Record) bool {
+		return len(r.PayloadBytes()) < 7
+	})
+	if len(short) != 3 {
+		t.Error("unexpected filtered records:", short)
+	}
+}
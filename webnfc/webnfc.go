@@ -0,0 +1,506 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+// Package webnfc converts NDEF Messages to and from the model used by
+// the W3C Web NFC API (https://w3c.github.io/web-nfc/), so that they
+// can be exchanged with browsers as JSON.
+//
+// A Message corresponds to an NDEFMessageInit and a Record to an
+// NDEFRecordInit:
+//
+//	{"records": [
+//	  {"recordType": "text", "lang": "en", "encoding": "utf-8", "data": "hi"},
+//	  {"recordType": "url", "data": "https://example.com"},
+//	  {"recordType": "smart-poster", "data": {"records": [...]}},
+//	  {"recordType": "mime", "mediaType": "image/png", "data": [137, 80, 78]},
+//	  {"recordType": "example.com:type", "id": "a", "data": [1, 2, 3]}
+//	]}
+//
+// Binary data is represented as an array of bytes, which is what
+// Array.from() returns for an Uint8Array in JavaScript. Strings are
+// accepted as binary data too, and are encoded in UTF-8.
+package webnfc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf16"
+
+	ndef "github.com/hsanjuan/go-ndef"
+	"github.com/hsanjuan/go-ndef/types/generic"
+	"github.com/hsanjuan/go-ndef/types/wkt/text"
+	"github.com/hsanjuan/go-ndef/types/wkt/uri"
+)
+
+// Record types defined by Web NFC. Other record types are either
+// external types ("example.com:type") or local types (":act").
+const (
+	Empty       = "empty"
+	Text        = "text"
+	URL         = "url"
+	Mime        = "mime"
+	AbsoluteURL = "absolute-url"
+	SmartPoster = "smart-poster"
+	Unknown     = "unknown"
+)
+
+// Text encodings supported by Web NFC.
+const (
+	UTF8    = "utf-8"
+	UTF16   = "utf-16"
+	UTF16BE = "utf-16be"
+	UTF16LE = "utf-16le"
+)
+
+// DefaultLang is the language of text records which do not set one.
+const DefaultLang = "en"
+
+// DefaultMediaType is the media type of mime records which do not set one.
+const DefaultMediaType = "application/octet-stream"
+
+// Errors returned when converting messages.
+var (
+	ErrNoRecords          = errors.New("webnfc: the message has no records")
+	ErrRecordType         = errors.New("webnfc: unsupported record type")
+	ErrLocalType          = errors.New("webnfc: local types are only allowed in nested messages")
+	ErrEncoding           = errors.New("webnfc: unsupported text encoding")
+	ErrData               = errors.New("webnfc: unsupported data for the record type")
+	ErrAbsoluteURLPayload = errors.New("webnfc: absolute-url records cannot carry a payload")
+)
+
+// Message represents a Web NFC NDEFMessageInit.
+type Message struct {
+	Records []*Record `json:"records"`
+}
+
+// Record represents a Web NFC NDEFRecordInit.
+//
+// Data is nil for empty records, a string for text, url and absolute-url
+// records, a *Message for smart-poster records and a []byte otherwise.
+// External and local records may use a *Message as Data too, which is
+// then their payload.
+type Record struct {
+	RecordType string
+	MediaType  string
+	ID         string
+	Encoding   string
+	Lang       string
+	Data       interface{}
+}
+
+type jsonRecord struct {
+	RecordType string          `json:"recordType"`
+	MediaType  string          `json:"mediaType,omitempty"`
+	ID         string          `json:"id,omitempty"`
+	Encoding   string          `json:"encoding,omitempty"`
+	Lang       string          `json:"lang,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// FromNDEF converts an NDEF Message to a Web NFC Message. An error is
+// returned if any of the records cannot be represented in Web NFC.
+func FromNDEF(m *ndef.Message) (*Message, error) {
+	return fromNDEF(m, false)
+}
+
+func fromNDEF(m *ndef.Message, nested bool) (*Message, error) {
+	if len(m.Records) == 0 {
+		return nil, ErrNoRecords
+	}
+	msg := &Message{}
+	for _, r := range m.Records {
+		wr, err := fromNDEFRecord(r, nested)
+		if err != nil {
+			return nil, err
+		}
+		msg.Records = append(msg.Records, wr)
+	}
+	return msg, nil
+}
+
+func fromNDEFRecord(r *ndef.Record, nested bool) (*Record, error) {
+	wr := &Record{ID: r.ID()}
+	payload := r.PayloadBytes()
+
+	switch r.TNF() {
+	case ndef.Empty:
+		wr.RecordType = Empty
+	case ndef.MediaType:
+		wr.RecordType = Mime
+		wr.MediaType = r.Type()
+		wr.Data = payload
+	case ndef.AbsoluteURI:
+		if len(payload) > 0 {
+			return nil, ErrAbsoluteURLPayload
+		}
+		wr.RecordType = AbsoluteURL
+		wr.Data = r.Type()
+	case ndef.NFCForumExternalType:
+		wr.RecordType = r.Type()
+		wr.Data = payload
+	case ndef.Unknown:
+		wr.RecordType = Unknown
+		wr.Data = payload
+	case ndef.NFCForumWellKnownType:
+		return fromWellKnown(r, wr, payload, nested)
+	default:
+		return nil, ErrRecordType
+	}
+	return wr, nil
+}
+
+func fromWellKnown(r *ndef.Record, wr *Record, payload []byte, nested bool) (*Record, error) {
+	typ := r.Type()
+	switch typ {
+	case "T":
+		t := new(text.Payload)
+		if err := t.Decode(payload); err != nil {
+			return nil, err
+		}
+		wr.RecordType = Text
+		wr.Encoding = textEncoding(payload)
+		wr.Lang = t.Language
+		wr.Data = t.Text
+	case "U":
+		u := new(uri.Payload)
+		if err := u.Decode(payload); err != nil {
+			return nil, err
+		}
+		wr.RecordType = URL
+		wr.Data = u.String()
+	case "Sp":
+		pl, err := r.Payload()
+		if err != nil {
+			return nil, err
+		}
+		sp, ok := pl.(*ndef.SmartPosterPayload)
+		if !ok {
+			return nil, ErrData
+		}
+		msg, err := fromNDEF(sp.Message, true)
+		if err != nil {
+			return nil, err
+		}
+		wr.RecordType = SmartPoster
+		wr.Data = msg
+	default:
+		if !ndef.IsLocalType(typ) {
+			return nil, ErrRecordType
+		}
+		if !nested {
+			return nil, ErrLocalType
+		}
+		wr.RecordType = ":" + typ
+		wr.Data = payload
+	}
+	return wr, nil
+}
+
+// textEncoding returns the encoding of a well-formed text payload.
+func textEncoding(payload []byte) string {
+	if payload[0]&0x80 == 0 {
+		return UTF8
+	}
+	text := payload[1+int(payload[0]&0x3F):]
+	if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+		return UTF16LE
+	}
+	return UTF16BE
+}
+
+// ToNDEF converts the Message to an NDEF Message. The MB and ME flags
+// are set on the first and last records. An error is returned if the
+// Message does not produce valid NDEF records.
+func (m *Message) ToNDEF() (*ndef.Message, error) {
+	return m.toNDEF(false)
+}
+
+func (m *Message) toNDEF(nested bool) (*ndef.Message, error) {
+	if len(m.Records) == 0 {
+		return nil, ErrNoRecords
+	}
+	var records []*ndef.Record
+	for _, wr := range m.Records {
+		if wr == nil {
+			return nil, ErrData
+		}
+		r, err := wr.toNDEF(nested)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	msg := ndef.NewMessageFromRecords(records...)
+	if _, err := msg.Marshal(); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (wr *Record) toNDEF(nested bool) (*ndef.Record, error) {
+	switch wr.RecordType {
+	case Empty:
+		if wr.Data != nil {
+			return nil, ErrData
+		}
+		return ndef.NewRecord(ndef.Empty, "", wr.ID, nil), nil
+	case Text:
+		payload, err := wr.textPayload()
+		if err != nil {
+			return nil, err
+		}
+		return ndef.NewRecord(ndef.NFCForumWellKnownType, "T", wr.ID,
+			generic.New(payload)), nil
+	case URL:
+		s, ok := wr.Data.(string)
+		if !ok {
+			return nil, ErrData
+		}
+		return ndef.NewRecord(ndef.NFCForumWellKnownType, "U", wr.ID,
+			uri.New(s)), nil
+	case AbsoluteURL:
+		s, ok := wr.Data.(string)
+		if !ok {
+			return nil, ErrData
+		}
+		return ndef.NewRecord(ndef.AbsoluteURI, s, wr.ID, nil), nil
+	case SmartPoster:
+		m, ok := wr.Data.(*Message)
+		if !ok {
+			return nil, ErrData
+		}
+		msg, err := m.toNDEF(true)
+		if err != nil {
+			return nil, err
+		}
+		return ndef.NewRecord(ndef.NFCForumWellKnownType, "Sp", wr.ID,
+			ndef.NewSmartPosterPayload(msg)), nil
+	case Mime:
+		payload, err := wr.bytes(false)
+		if err != nil {
+			return nil, err
+		}
+		mediaType := wr.MediaType
+		if mediaType == "" {
+			mediaType = DefaultMediaType
+		}
+		return ndef.NewRecord(ndef.MediaType, mediaType, wr.ID,
+			generic.New(payload)), nil
+	case Unknown:
+		payload, err := wr.bytes(false)
+		if err != nil {
+			return nil, err
+		}
+		return ndef.NewRecord(ndef.Unknown, "", wr.ID,
+			generic.New(payload)), nil
+	}
+
+	switch i := strings.IndexByte(wr.RecordType, ':'); {
+	case i == 0:
+		typ := wr.RecordType[1:]
+		if !ndef.IsLocalType(typ) {
+			return nil, ErrRecordType
+		}
+		if !nested {
+			return nil, ErrLocalType
+		}
+		payload, err := wr.bytes(true)
+		if err != nil {
+			return nil, err
+		}
+		return ndef.NewRecord(ndef.NFCForumWellKnownType, typ, wr.ID,
+			generic.New(payload)), nil
+	case i > 0:
+		payload, err := wr.bytes(true)
+		if err != nil {
+			return nil, err
+		}
+		return ndef.NewRecord(ndef.NFCForumExternalType, wr.RecordType,
+			wr.ID, generic.New(payload)), nil
+	default:
+		return nil, ErrRecordType
+	}
+}
+
+// bytes returns the Data of records with binary payloads. Strings are
+// encoded in UTF-8, and nested messages are allowed when withMessage
+// is set.
+func (wr *Record) bytes(withMessage bool) ([]byte, error) {
+	switch data := wr.Data.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return data, nil
+	case string:
+		return []byte(data), nil
+	case *Message:
+		if !withMessage {
+			return nil, ErrData
+		}
+		msg, err := data.toNDEF(true)
+		if err != nil {
+			return nil, err
+		}
+		return msg.Marshal()
+	default:
+		return nil, ErrData
+	}
+}
+
+// textPayload returns the payload of a text record in the given
+// encoding. Binary Data is taken as text already in that encoding.
+func (wr *Record) textPayload() ([]byte, error) {
+	lang := wr.Lang
+	if lang == "" {
+		lang = DefaultLang
+	}
+	if len(lang) > 0x3F {
+		return nil, ErrData
+	}
+
+	var status byte
+	var txt []byte
+	switch data := wr.Data.(type) {
+	case string:
+		switch strings.ToLower(wr.Encoding) {
+		case "", UTF8:
+			txt = []byte(data)
+		case UTF16, UTF16BE:
+			status = 0x80
+			txt = encodeUTF16(data, binary.BigEndian)
+		case UTF16LE:
+			status = 0x80
+			// little-endian needs a byte order mark
+			txt = encodeUTF16("\uFEFF"+data, binary.LittleEndian)
+		default:
+			return nil, ErrEncoding
+		}
+	case []byte:
+		switch strings.ToLower(wr.Encoding) {
+		case "", UTF8:
+		case UTF16, UTF16BE, UTF16LE:
+			status = 0x80
+		default:
+			return nil, ErrEncoding
+		}
+		txt = data
+	default:
+		return nil, ErrData
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(status | byte(len(lang)))
+	buf.WriteString(lang)
+	buf.Write(txt)
+	return buf.Bytes(), nil
+}
+
+func encodeUTF16(s string, order binary.ByteOrder) []byte {
+	units := utf16.Encode([]rune(s))
+	buf := make([]byte, 2*len(units))
+	for i, u := range units {
+		order.PutUint16(buf[2*i:], u)
+	}
+	return buf
+}
+
+// MarshalJSON returns the JSON representation of the Record, as
+// described in the package documentation.
+func (wr *Record) MarshalJSON() ([]byte, error) {
+	jr := jsonRecord{
+		RecordType: wr.RecordType,
+		MediaType:  wr.MediaType,
+		ID:         wr.ID,
+		Encoding:   wr.Encoding,
+		Lang:       wr.Lang,
+	}
+
+	var err error
+	switch data := wr.Data.(type) {
+	case nil:
+	case []byte:
+		// an array of numbers, rather than base64.
+		ints := make([]int, len(data))
+		for i, b := range data {
+			ints[i] = int(b)
+		}
+		jr.Data, err = json.Marshal(ints)
+	case string, *Message:
+		jr.Data, err = json.Marshal(data)
+	default:
+		return nil, ErrData
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jr)
+}
+
+// UnmarshalJSON parses the JSON representation of a Record. Data is set
+// to a string, a []byte or a *Message depending on its JSON type.
+func (wr *Record) UnmarshalJSON(data []byte) error {
+	var jr jsonRecord
+	if err := json.Unmarshal(data, &jr); err != nil {
+		return err
+	}
+
+	var value interface{}
+	raw := bytes.TrimSpace(jr.Data)
+	switch {
+	case len(raw) == 0 || bytes.Equal(raw, []byte("null")):
+	case raw[0] == '"':
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		value = s
+	case raw[0] == '[':
+		var ints []int
+		if err := json.Unmarshal(raw, &ints); err != nil {
+			return err
+		}
+		bs := make([]byte, len(ints))
+		for i, n := range ints {
+			if n < 0 || n > 0xFF {
+				return ErrData
+			}
+			bs[i] = byte(n)
+		}
+		value = bs
+	case raw[0] == '{':
+		msg := &Message{}
+		if err := json.Unmarshal(raw, msg); err != nil {
+			return err
+		}
+		value = msg
+	default:
+		return ErrData
+	}
+
+	*wr = Record{
+		RecordType: jr.RecordType,
+		MediaType:  jr.MediaType,
+		ID:         jr.ID,
+		Encoding:   jr.Encoding,
+		Lang:       jr.Lang,
+		Data:       value,
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+/***
+    Copyright (c) 2018, Hector Sanjuan
+
+    This program is free software: you can redistribute it and/or modify
+    it under the terms of the GNU Lesser General Public License as published by
+    the Free Software Foundation, either version 3 of the License, or
+    (at your option) any later version.
+
+    This program is distributed in the hope that it will be useful,
+    but WITHOUT ANY WARRANTY; without even the implied warranty of
+    MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+    GNU Lesser General Public License for more details.
+
+    You should have received a copy of the GNU Lesser General Public License
+    along with this program.  If not, see <http://www.gnu.org/licenses/>.
+***/
+
+package webnfc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	ndef "github.com/hsanjuan/go-ndef"
+	"github.com/hsanjuan/go-ndef/types/generic"
+)
+
+func TestFromNDEF(t *testing.T) {
+	action := ndef.NewRecord(ndef.NFCForumWellKnownType, "act", "",
+		generic.New([]byte{0}))
+	ext := ndef.NewExternalRecord("example.com:type", []byte{1, 2})
+	if err := ext.SetID("a"); err != nil {
+		t.Fatal(err)
+	}
+	m := ndef.NewMessageFromRecords(
+		ndef.NewTextRecord("hi", "en"),
+		ndef.NewSmartPosterRecord(ndef.NewMessageFromRecords(
+			ndef.NewURIRecord("https://example.com"),
+			action,
+		)),
+		ndef.NewMediaRecord("a/b", []byte{3}),
+		ndef.NewAbsoluteURIRecord("https://example.com/t", nil),
+		ext,
+		ndef.NewRecord(ndef.Unknown, "", "", generic.New([]byte{4})),
+		ndef.NewRecord(ndef.Empty, "", "", nil),
+	)
+
+	wm, err := FromNDEF(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	js, err := json.Marshal(wm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"records":[` +
+		`{"recordType":"text","encoding":"utf-8","lang":"en","data":"hi"},` +
+		`{"recordType":"smart-poster","data":{"records":[` +
+		`{"recordType":"url","data":"https://example.com"},` +
+		`{"recordType":":act","data":[0]}]}},` +
+		`{"recordType":"mime","mediaType":"a/b","data":[3]},` +
+		`{"recordType":"absolute-url","data":"https://example.com/t"},` +
+		`{"recordType":"example.com:type","id":"a","data":[1,2]},` +
+		`{"recordType":"unknown","data":[4]},` +
+		`{"recordType":"empty"}]}`
+	if string(js) != expected {
+		t.Error("unexpected JSON:", string(js))
+	}
+
+	wm2 := &Message{}
+	if err := json.Unmarshal(js, wm2); err != nil {
+		t.Fatal(err)
+	}
+	m2, err := wm2.ToNDEF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b1, _ := m.Marshal()
+	b2, err := m2.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b1, b2) {
+		t.Error("round trip should produce identical bytes")
+	}
+}
+
+func TestFromNDEFErrors(t *testing.T) {
+	tcs := []struct {
+		r   *ndef.Record
+		err error
+	}{
+		{ndef.NewAbsoluteURIRecord("https://example.com/t", []byte{1}),
+			ErrAbsoluteURLPayload},
+		{ndef.NewRecord(ndef.NFCForumWellKnownType, "act", "", nil),
+			ErrLocalType},
+		{ndef.NewRecord(ndef.NFCForumWellKnownType, "Hs", "", nil),
+			ErrRecordType},
+	}
+	for _, tc := range tcs {
+		_, err := FromNDEF(ndef.NewMessageFromRecords(tc.r))
+		if !errors.Is(err, tc.err) {
+			t.Errorf("expected %s, got %v", tc.err, err)
+		}
+	}
+}
+
+func TestTextEncodings(t *testing.T) {
+	tcs := []struct {
+		encoding string
+		payload  []byte
+	}{
+		{"", []byte{0x02, 'e', 'n', 'h', 'i'}},
+		{UTF16, []byte{0x82, 'e', 'n', 0, 'h', 0, 'i'}},
+		{UTF16LE, []byte{0x82, 'e', 'n', 0xFF, 0xFE, 'h', 0, 'i', 0}},
+	}
+	for _, tc := range tcs {
+		wm := &Message{Records: []*Record{{
+			RecordType: Text,
+			Encoding:   tc.encoding,
+			Data:       "hi",
+		}}}
+		m, err := wm.ToNDEF()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pl := m.Records[0].PayloadBytes(); !bytes.Equal(pl, tc.payload) {
+			t.Errorf("%s: unexpected payload %x", tc.encoding, pl)
+		}
+
+		wm2, err := FromNDEF(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := wm2.Records[0]
+		if r.Data != "hi" || r.Lang != DefaultLang {
+			t.Error("unexpected record:", r)
+		}
+		if tc.encoding == UTF16 && r.Encoding != UTF16BE {
+			t.Error("expected big-endian UTF-16:", r.Encoding)
+		}
+	}
+}
+
+func TestToNDEFErrors(t *testing.T) {
+	tcs := []struct {
+		js  string
+		err error
+	}{
+		{`{"records":[]}`, ErrNoRecords},
+		{`{"records":[{"recordType":"foo"}]}`, ErrRecordType},
+		{`{"records":[{"recordType":":act","data":[0]}]}`, ErrLocalType},
+		{`{"records":[{"recordType":"url","data":[0]}]}`, ErrData},
+		{`{"records":[{"recordType":"mime","data":{"records":[]}}]}`, ErrData},
+		{`{"records":[{"recordType":"text","encoding":"latin1","data":"a"}]}`,
+			ErrEncoding},
+		{`{"records":[{"recordType":"smart-poster","data":{"records":[]}}]}`,
+			ErrNoRecords},
+		{`{"records":[{"recordType":"empty","id":"a"}]}`, ndef.ErrNotEmpty},
+	}
+	for _, tc := range tcs {
+		wm := &Message{}
+		if err := json.Unmarshal([]byte(tc.js), wm); err != nil {
+			t.Fatal(err)
+		}
+		_, err := wm.ToNDEF()
+		if !errors.Is(err, tc.err) {
+			t.Errorf("%s: expected %s, got %v", tc.js, tc.err, err)
+		}
+	}
+
+	wm := &Message{}
+	err := json.Unmarshal([]byte(`{"records":[{"recordType":"mime","data":[256]}]}`), wm)
+	if !errors.Is(err, ErrData) {
+		t.Error("expected an error for bytes out of range")
+	}
+}
+
+func TestExternalMessage(t *testing.T) {
+	js := `{"records":[{"recordType":"example.com:t","data":{"records":[` +
+		`{"recordType":":act","data":[1]}]}}]}`
+	wm := &Message{}
+	if err := json.Unmarshal([]byte(js), wm); err != nil {
+		t.Fatal(err)
+	}
+	m, err := wm.ToNDEF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	inner := &ndef.Message{}
+	if _, err := inner.Unmarshal(m.Records[0].PayloadBytes()); err != nil {
+		t.Fatal(err)
+	}
+	if inner.Records[0].Type() != "act" {
+		t.Error("the payload should be the nested message")
+	}
+}